@@ -12,24 +12,24 @@ type Annotation struct {
 
 	// Body of the annotation. Optional.
 	Body *ObjectBody
+
+	Span
 }
 
 func (a *Annotation) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	name, err := a.Name.Marshal(ctx)
+	name, err := marshalField(ctx, a, "Name", a.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix("@", name)
 
-	if a.Body != nil {
-		body, err := a.Body.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(" ", body)
+	body, err := marshalOptionalField(ctx, a, "Body", a.Body)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(" ", body)
 
 	return b.Bytes(), nil
 }
@@ -37,5 +37,5 @@ func (a *Annotation) Marshal(ctx context.Context) ([]byte, error) {
 type Annotations []*Annotation
 
 func (a Annotations) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, a, newlineWithIndentation(ctx))
+	return joinNodes(ctx, a, "", a, newlineWithIndentation(ctx))
 }
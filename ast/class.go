@@ -23,73 +23,77 @@ type Class struct {
 	ParentTypeParameters TypeParameters
 	// Optional.
 	Members []ClassMember
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (c *Class) isModuleMember() {}
 
 func (c *Class) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-
-	docs, err := c.Docs.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(docs, newlineWithIndentation(ctx))
+	return marshalNode(ctx, c)
+}
 
-	annotations, err := c.Annotations.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(annotations, newlineWithIndentation(ctx))
+func (c *Class) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeWithComments(ctx, b, c, c.LeadingComments, c.TrailingComment, func() error {
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", c, "Docs", c.Docs, newlineWithIndentation(ctx)); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", c, "Annotations", c.Annotations, newlineWithIndentation(ctx)); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", c, "Modifiers", c.Modifiers, " "); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, "class ", c, "Name", c.Name, ""); err != nil {
+			return err
+		}
+		if err := writeField(ctx, b, c, "TypeParameters", c.TypeParameters); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, " extends ", c, "ParentName", c.ParentName, ""); err != nil {
+			return err
+		}
+		if err := writeField(ctx, b, c, "ParentTypeParameters", c.ParentTypeParameters); err != nil {
+			return err
+		}
 
-	modifiers, err := c.Modifiers.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(modifiers, " ")
+		membersCtx := raiseIndentLevel(ctx)
+		return writeWithPrefixSuffix(
+			b,
+			" {"+newlineWithIndentation(membersCtx),
+			func() error { return writeMembers(membersCtx, b, c, "Members", c.Members, true) },
+			newlineWithIndentation(ctx)+"}",
+		)
+	})
+}
 
-	name, err := c.Name.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefix("class ", name)
+type ClassMember interface {
+	Node
+	isClassMember()
+}
 
-	typeParams, err := c.TypeParameters.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.Write(typeParams)
+// ClassMemberGroup is a group of related class members. Unlike other class
+// members, the members of a group are not separated by blank lines.
+type ClassMemberGroup struct {
+	// Required.
+	Members []ClassMember
 
-	parentName, err := c.ParentName.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefix(" extends ", parentName)
+	Span
+}
 
-	parentTypeParams, err := c.ParentTypeParameters.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.Write(parentTypeParams)
+func (g *ClassMemberGroup) isClassMember() {}
+func (g *ClassMemberGroup) isMemberGroup() {}
 
-	membersCtx := raiseIndentLevel(ctx)
-	membersSep := "\n" + newlineWithIndentation(membersCtx)
-	members, err := joinNodes(membersCtx, c.Members, membersSep)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefixSuffix(
-		" {"+newlineWithIndentation(membersCtx),
-		members,
-		newlineWithIndentation(ctx)+"}",
-	)
-
-	return b.Bytes(), nil
+func (g *ClassMemberGroup) Marshal(ctx context.Context) ([]byte, error) {
+	return marshalNode(ctx, g)
 }
 
-type ClassMember interface {
-	Node
-	isClassMember()
+func (g *ClassMemberGroup) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeMembers(ctx, b, g, "Members", g.Members, false)
 }
 
 // ClassProperty represents a property declaration in a class or module.
@@ -111,63 +115,50 @@ type ClassProperty struct {
 	// Can not be set together with Type or Expression. Required if both
 	// Type and Expression are nil.
 	Body *ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (p *ClassProperty) isModuleMember() {}
 func (p *ClassProperty) isClassMember()  {}
 
 func (p *ClassProperty) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-
-	docs, err := p.Docs.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(docs, newlineWithIndentation(ctx))
-
-	annotations, err := p.Annotations.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(annotations, newlineWithIndentation(ctx))
-
-	modifiers, err := p.Modifiers.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(modifiers, " ")
+	return marshalNode(ctx, p)
+}
 
-	name, err := p.Name.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.Write(name)
+func (p *ClassProperty) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeWithComments(ctx, b, p, p.LeadingComments, p.TrailingComment, func() error {
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", p, "Docs", p.Docs, newlineWithIndentation(ctx)); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", p, "Annotations", p.Annotations, newlineWithIndentation(ctx)); err != nil {
+			return err
+		}
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", p, "Modifiers", p.Modifiers, " "); err != nil {
+			return err
+		}
+		if err := writeField(ctx, b, p, "Name", p.Name); err != nil {
+			return err
+		}
 
-	if p.Type != nil {
-		typ, err := p.Type.Marshal(ctx)
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, ": ", p, "Type", p.Type, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(": ", typ)
-	}
 
-	if p.Expression != nil {
-		expr, err := p.Expression.Marshal(ctx)
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, " = ", p, "Expression", p.Expression, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(" = ", expr)
-	}
 
-	if p.Body != nil {
-		body, err := p.Body.Marshal(ctx)
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, " ", p, "Body", p.Body, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(" ", body)
-	}
 
-	return b.Bytes(), nil
+		return nil
+	})
 }
 
 type MethodSignature struct {
@@ -181,46 +172,45 @@ type MethodSignature struct {
 	Parameters Parameters
 	// Optional.
 	Result Type
+
+	Span
 }
 
 func (m *MethodSignature) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
 
-	modifiers, err := m.Modifiers.Marshal(ctx)
+	modifiers, err := marshalField(ctx, m, "Modifiers", m.Modifiers)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(modifiers, " ")
 
-	name, err := m.Name.Marshal(ctx)
+	name, err := marshalField(ctx, m, "Name", m.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix("function ", name)
 
-	typeParams, err := m.TypeParameters.Marshal(ctx)
+	typeParams, err := marshalField(ctx, m, "TypeParameters", m.TypeParameters)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(typeParams)
 
-	params, err := joinNodes(ctx, m.Parameters, ", ")
+	// The parameters are wrapped like the arguments of a call.
+	params, err := marshalNodes(ctx, m, "Parameters", m.Parameters)
 	if err != nil {
 		return nil, err
 	}
-	b.WriteRune('(')
-	b.Write(params)
-	b.WriteRune(')')
-
-	if m.Result != nil {
-		result, err := m.Result.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(": ", result)
+	b.writeList("(", params, ")")
+
+	result, err := marshalOptionalField(ctx, m, "Result", m.Result)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(": ", result)
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 // ClassMethod represents a method declaration with an optional implementation
@@ -234,6 +224,12 @@ type ClassMethod struct {
 	Signature *MethodSignature
 	// Optional.
 	Implementation Expression
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ClassMethod) isModuleMember() {}
@@ -242,31 +238,29 @@ func (m *ClassMethod) isClassMember()  {}
 func (m *ClassMethod) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	docs, err := m.Docs.Marshal(ctx)
+	docs, err := marshalField(ctx, m, "Docs", m.Docs)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(docs, newlineWithIndentation(ctx))
 
-	annotations, err := m.Annotations.Marshal(ctx)
+	annotations, err := marshalField(ctx, m, "Annotations", m.Annotations)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(annotations, newlineWithIndentation(ctx))
 
-	signature, err := m.Signature.Marshal(ctx)
+	signature, err := marshalField(ctx, m, "Signature", m.Signature)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(signature)
 
-	if m.Implementation != nil {
-		impl, err := m.Implementation.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(" = ", impl)
+	impl, err := marshalOptionalField(ctx, m, "Implementation", m.Implementation)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(" = ", impl)
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
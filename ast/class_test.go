@@ -357,6 +357,29 @@ func TestClassMarshal(t *testing.T) {
 				|}
 			`),
 		},
+		{
+			name: "with member groups",
+			node: Class{
+				Name: Identifier("Server"),
+				Members: []ClassMember{
+					&ClassMemberGroup{
+						Members: []ClassMember{
+							&ClassProperty{Name: Identifier("host"), Type: &DeclaredType{Name: "String"}},
+							&ClassProperty{Name: Identifier("port"), Type: &DeclaredType{Name: "Int"}},
+						},
+					},
+					&ClassProperty{Name: Identifier("debug"), Type: &DeclaredType{Name: "Boolean"}},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|class Server {
+				|  host: String
+				|  port: Int
+				|
+				|  debug: Boolean
+				|}
+			`),
+		},
 	}
 
 	for _, test := range tests {
@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"context"
+	"strings"
 
 	"github.com/pauloborges/balsamic/internal/bytesutil"
 )
@@ -12,39 +13,61 @@ type Comment interface {
 	isComment()
 }
 
+// LineComment is a "//" comment. Each line of the text is printed as a
+// separate comment line, and an empty text as a bare "//".
+//
+// Like BlockComment, it can be used as a module, class or object member, to
+// print a comment that is not attached to any other member.
 type LineComment string
 
-func (c LineComment) isComment() {}
+func (c LineComment) isComment()      {}
+func (c LineComment) isModuleMember() {}
+func (c LineComment) isClassMember()  {}
+func (c LineComment) isObjectMember() {}
 
 func (c LineComment) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-	prefix := indentation(ctx) + "// "
-	b.WriteWithPrefix(prefix, []byte(c))
+	var b bytes.Buffer
+
+	for i, line := range strings.Split(string(c), "\n") {
+		if i > 0 {
+			b.WriteString(newlineWithIndentation(ctx))
+		}
+		if len(line) > 0 {
+			b.WriteString("// ")
+			b.WriteString(escapeDoc(ctx, line))
+		} else {
+			b.WriteString("//")
+		}
+	}
+
 	return b.Bytes(), nil
 }
 
 type BlockComment string
 
-func (c BlockComment) isComment() {}
+func (c BlockComment) isComment()      {}
+func (c BlockComment) isModuleMember() {}
+func (c BlockComment) isClassMember()  {}
+func (c BlockComment) isObjectMember() {}
 
 func (c BlockComment) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytes.Buffer
-
 	if c == "" {
-		return nil, nil
+		return []byte("/**/"), nil
 	}
 
+	var b bytes.Buffer
+
 	b.WriteString("/*")
 	b.WriteString(newlineWithIndentation(ctx))
 
-	for i, line := range bytes.Split([]byte(string(c)), []byte{'\n'}) {
+	for i, line := range strings.Split(string(c), "\n") {
 		if i > 0 {
 			b.WriteString(newlineWithIndentation(ctx))
 		}
 		if len(line) > 0 {
-			b.WriteString("  ")
+			b.WriteString(indentUnit(ctx))
 		}
-		b.Write(line)
+		b.WriteString(escapeDoc(ctx, line))
 	}
 
 	b.WriteString(newlineWithIndentation(ctx))
@@ -64,13 +87,13 @@ func (c Docs) Marshal(ctx context.Context) ([]byte, error) {
 
 	var b bytes.Buffer
 
-	for i, line := range bytes.Split([]byte(string(c)), []byte{'\n'}) {
+	for i, line := range strings.Split(string(c), "\n") {
 		if i > 0 {
 			b.WriteString(newlineWithIndentation(ctx))
 		}
 		if len(line) > 0 {
 			b.WriteString("/// ")
-			b.Write(line)
+			b.WriteString(escapeDoc(ctx, line))
 		} else {
 			b.WriteString("///")
 		}
@@ -79,6 +102,52 @@ func (c Docs) Marshal(ctx context.Context) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// Comments is a list of comments printed in their own lines, such as the
+// comments leading a member.
+type Comments []Comment
+
+func (c Comments) Marshal(ctx context.Context) ([]byte, error) {
+	return joinNodes(ctx, c, "", c, newlineWithIndentation(ctx))
+}
+
+// writeWithComments writes a member n to b, written by write, surrounded by its
+// leading comments, in the lines before it, and its trailing comment, at the
+// end of its last line.
+func writeWithComments(ctx context.Context, b *bytesutil.Buffer, n Node, leading Comments, trailing LineComment, write func() error) error {
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", n, "LeadingComments", leading, newlineWithIndentation(ctx)); err != nil {
+		return err
+	}
+
+	if err := write(); err != nil {
+		return err
+	}
+
+	// An empty trailing comment is not set, unlike an empty leading one.
+	if trailing == "" {
+		return nil
+	}
+	return writeFieldWithPrefixSuffix(ctx, b, " ", n, "TrailingComment", trailing, "")
+}
+
+// withComments returns the source code of a member n surrounded by its leading
+// comments and its trailing comment, like writeWithComments.
+func withComments(ctx context.Context, n Node, leading Comments, trailing LineComment, member []byte) ([]byte, error) {
+	if len(leading) == 0 && trailing == "" {
+		return member, nil
+	}
+
+	var b bytesutil.Buffer
+	err := writeWithComments(ctx, &b, n, leading, trailing, func() error {
+		b.Write(member)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
 type ShebangComment string
 
 func (c ShebangComment) isComment() {}
@@ -19,18 +19,30 @@ func TestLineCommentMarshal(t *testing.T) {
 		{
 			name: "empty",
 			node: "",
-			res:  "",
+			res:  "//",
 		},
 		{
 			name: "single line",
 			node: "This is a comment",
 			res:  "// This is a comment",
 		},
+		{
+			name: "multi line with empty line",
+			node: "This is a comment\n\nThis is another comment",
+			res: stringsutil.StripMargin(`
+				|// This is a comment
+				|//
+				|// This is another comment
+			`),
+		},
 		{
 			name:        "indented",
 			indentLevel: 1,
-			node:        "This is a comment",
-			res:         "  // This is a comment",
+			node:        "This is a comment\nThis is another comment",
+			res: stringsutil.StripMargin(`
+				|// This is a comment
+				|  // This is another comment
+			`),
 		},
 	}
 
@@ -60,7 +72,7 @@ func TestBlockCommentMarshal(t *testing.T) {
 		{
 			name: "empty",
 			node: "",
-			res:  "",
+			res:  "/**/",
 		},
 		{
 			name: "single line",
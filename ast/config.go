@@ -0,0 +1,113 @@
+package ast
+
+import (
+	"context"
+	"strings"
+)
+
+// LineEnding is the line ending style of printed source code.
+type LineEnding int
+
+const (
+	LineEndingLF LineEnding = iota
+	LineEndingCRLF
+)
+
+// PrintConfig configures how nodes are pretty-printed. Marshal reads it from
+// the context, set with WithPrintConfig, and uses DefaultPrintConfig if there
+// is none.
+type PrintConfig struct {
+	// Number of spaces per indentation level. Ignored if UseTabs is set.
+	IndentWidth uint
+	// Whether to indent with a tab per indentation level.
+	UseTabs bool
+	// Whether the printed source code ends with a line break. Only applied
+	// by printer.Fprint.
+	FinalNewline bool
+	// Line ending of the printed source code. Only applied by
+	// printer.Fprint, Marshal always ends lines with "\n".
+	LineEnding LineEnding
+	// Number of blank lines between module members, between class members,
+	// and around member groups.
+	BlankLines uint
+	// Maximum width of the lines, in columns, that call arguments, type
+	// parameters, union types, binary operator chains and method call
+	// chains are wrapped to fit in. Zero disables wrapping. Tabs take
+	// IndentWidth columns.
+	MaxWidth uint
+	// Whether to drop the parentheses of expressions that do not need them.
+	// The parentheses needed by the precedence of operators are always
+	// printed.
+	StripParentheses bool
+	// Whether to print the strings that have quotes or backslashes between
+	// custom delimiters, such as #"C:\path"#, so they need no escaping.
+	CustomDelimiters bool
+	// Whether to print the modifiers of declarations in Pkl's canonical
+	// order, as in Modifiers.Sorted.
+	SortModifiers bool
+}
+
+// DefaultPrintConfig is the configuration used when the context has none.
+var DefaultPrintConfig = PrintConfig{
+	IndentWidth:  2,
+	FinalNewline: true,
+	LineEnding:   LineEndingLF,
+	BlankLines:   1,
+}
+
+// Line breaks followed by indentation, sliced to print the lines of the
+// usual indentation levels without allocating.
+var (
+	spaceNewlines = "\n" + strings.Repeat(" ", 256)
+	tabNewlines   = "\n" + strings.Repeat("\t", 64)
+)
+
+// indentUnit returns the indentation of a single indentation level.
+func (c PrintConfig) indentUnit() string {
+	return c.newline(1)[1:]
+}
+
+// newline returns a line break followed by the indentation of indentLevel.
+func (c PrintConfig) newline(indentLevel uint) string {
+	n, newlines := int(indentLevel), tabNewlines
+	if !c.UseTabs {
+		n, newlines = n*int(c.IndentWidth), spaceNewlines
+	}
+	if n < len(newlines) {
+		return newlines[:1+n]
+	}
+	return "\n" + strings.Repeat(newlines[1:2], n)
+}
+
+// WithPrintConfig returns a copy of ctx that makes Marshal print nodes using
+// config.
+func WithPrintConfig(ctx context.Context, config PrintConfig) context.Context {
+	state := getPrintState(ctx)
+	state.config = config
+	state.newline = config.newline(state.indentLevel)
+	return context.WithValue(ctx, printStateKey, state)
+}
+
+// printState is the state of the printing of a node.
+type printState struct {
+	config      PrintConfig
+	indentLevel uint
+	// A line break followed by the indentation of indentLevel, computed once
+	// per level as it starts every line.
+	newline string
+	// Whether the node is part of a document laid out by an outer node.
+	inDoc bool
+}
+
+const printStateKey ctxKey = "printState"
+
+func getPrintState(ctx context.Context) printState {
+	if state, ok := ctx.Value(printStateKey).(printState); ok {
+		return state
+	}
+	return printState{config: DefaultPrintConfig, newline: "\n"}
+}
+
+func getPrintConfig(ctx context.Context) PrintConfig {
+	return getPrintState(ctx).config
+}
@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPrintConfig(t *testing.T) {
+	node := &Class{
+		Name: Identifier("Foo"),
+		Members: []ClassMember{
+			&ClassProperty{
+				LeadingComments: Comments{BlockComment("Bar.")},
+				Name:            Identifier("bar"),
+				Type:            &DeclaredType{Name: "String"},
+			},
+			&ClassProperty{
+				Name: Identifier("baz"),
+				Type: &DeclaredType{Name: "Int"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		indentLevel uint
+		config      PrintConfig
+		res         string
+	}{
+		{
+			name:   "default",
+			config: DefaultPrintConfig,
+			res: stringsutil.StripMargin(`
+				|class Foo {
+				|  /*
+				|    Bar.
+				|  */
+				|  bar: String
+				|
+				|  baz: Int
+				|}
+			`),
+		},
+		{
+			name:   "tabs",
+			config: PrintConfig{UseTabs: true, BlankLines: 1},
+			res:    "class Foo {\n\t/*\n\t\tBar.\n\t*/\n\tbar: String\n\n\tbaz: Int\n}",
+		},
+		{
+			name:        "indent width and level",
+			indentLevel: 1,
+			config:      PrintConfig{IndentWidth: 4},
+			res: stringsutil.StripMargin(`
+				|class Foo {
+				|        /*
+				|            Bar.
+				|        */
+				|        bar: String
+				|        baz: Int
+				|    }
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Given
+			ctx := ctxWithIndentLevel(context.Background(), test.indentLevel)
+			ctx = WithPrintConfig(ctx, test.config)
+
+			// When
+			result, err := node.Marshal(ctx)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(result))
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package ast
 
 import (
+	"bytes"
 	"context"
+	"math"
 	"strconv"
 
 	"github.com/pauloborges/balsamic/internal/bytesutil"
@@ -15,11 +17,121 @@ type Expression interface {
 type Expressions []Expression
 
 func (e Expressions) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, e, ", ")
+	return joinNodes(ctx, e, "", e, ", ")
 }
 
 var NoArguments = Expressions{}
 
+// precedence returns the precedence of an expression when used as an
+// operand.
+func precedence(e Expression) int {
+	switch e := e.(type) {
+	case *BinaryExpression:
+		return e.Operator.Precedence()
+	case *TypeExpression:
+		return precTypeTest
+	case *PrefixUnaryExpression:
+		return precPrefix
+	case *PostfixUnaryExpression:
+		return precPostfix
+	case *IfExpression, *LetExpression, *FunctionLiteralExpression:
+		return precLowest
+	// Negative numbers are printed with a prefix minus.
+	case IntExpression:
+		if e < 0 {
+			return precPrefix
+		}
+	case *FormattedIntExpression:
+		if e.Value < 0 {
+			return precPrefix
+		}
+	case FloatExpression:
+		if math.Signbit(float64(e)) && !math.IsNaN(float64(e)) {
+			return precPrefix
+		}
+	}
+	return precPrimary
+}
+
+// unparenthesize returns e, at path from the node being marshaled, without
+// the parentheses around it, if redundant parentheses are stripped, and the
+// path to the expression inside them.
+func unparenthesize(ctx context.Context, path string, e Expression) (string, Expression) {
+	if !getPrintConfig(ctx).StripParentheses {
+		return path, e
+	}
+	for {
+		paren, ok := e.(*ParenthesizedExpression)
+		if !ok || paren == nil || isNil(paren.Expression) {
+			return path, e
+		}
+		path, e = field(path, "Expression"), paren.Expression
+	}
+}
+
+// marshalOperand marshals the operand of a field of parent in parentheses if
+// its precedence is lower than prec. If, let and function literal
+// expressions extend as far to the right as possible, so they only need no
+// parentheses as the rightmost operand of parent, and any other operand
+// ending with them needs parentheses.
+func marshalOperand(ctx context.Context, parent Node, field string, e Expression, prec int, rightmost bool) ([]byte, error) {
+	field, e = unparenthesize(ctx, field, e)
+
+	b, err := marshalField(ctx, parent, field, e)
+	if err != nil {
+		return nil, err
+	}
+	if rightmost && isOpenEnded(e) {
+		return b, nil
+	}
+	if precedence(e) < prec || !rightmost && endsOpenEnded(ctx, e) {
+		return parenthesize(b), nil
+	}
+	return b, nil
+}
+
+// isOpenEnded reports whether e extends as far to the right as possible.
+func isOpenEnded(e Expression) bool {
+	switch e.(type) {
+	case *IfExpression, *LetExpression, *FunctionLiteralExpression:
+		return true
+	}
+	return false
+}
+
+// endsOpenEnded reports whether e is printed ending with an open-ended
+// expression without parentheses.
+func endsOpenEnded(ctx context.Context, e Expression) bool {
+	switch e := e.(type) {
+	case *BinaryExpression:
+		_, prec := e.Operator.OperandPrecedences()
+		return operandEndsOpenEnded(ctx, e.Right, prec)
+	case *PrefixUnaryExpression:
+		if operand, ok := e.Operand.(*PrefixUnaryExpression); ok && operand != nil && e.Operator == UnaryOperandLogicalNot && operand.Operator == UnaryOperandLogicalNot {
+			return false
+		}
+		return operandEndsOpenEnded(ctx, e.Operand, precPrefix)
+	}
+	return isOpenEnded(e)
+}
+
+// operandEndsOpenEnded reports whether the rightmost operand e of an
+// expression, printed as marshalOperand does, ends with an open-ended
+// expression.
+func operandEndsOpenEnded(ctx context.Context, e Expression, prec int) bool {
+	_, e = unparenthesize(ctx, "", e)
+	if isOpenEnded(e) {
+		return true
+	}
+	return precedence(e) >= prec && endsOpenEnded(ctx, e)
+}
+
+func parenthesize(b []byte) []byte {
+	var buf bytesutil.Buffer
+	buf.WriteWithPrefixSuffix("(", b, ")")
+	return buf.Bytes()
+}
+
 type BuiltinExpression string
 
 const (
@@ -46,13 +158,36 @@ func (e IntExpression) Marshal(_ context.Context) ([]byte, error) {
 	return []byte(strconv.FormatInt(int64(e), 10)), nil
 }
 
-// FloatExpression represents a floating-point literal.
+// FormattedIntExpression is an integer literal written in a given radix, or
+// with its digits grouped by underscores, such as 0xFF or 1_000_000.
+type FormattedIntExpression struct {
+	// Required.
+	Value int64
+	// Optional. Decimal by default.
+	Radix IntRadix
+	// Optional. The minimum number of digits, padded with leading zeros.
+	Digits uint
+	// Optional. The number of digits of each group separated by underscores,
+	// counting from the right. The digits are not grouped by default.
+	GroupSize uint
+
+	Span
+}
+
+func (e *FormattedIntExpression) isExpression() {}
+
+func (e *FormattedIntExpression) Marshal(_ context.Context) ([]byte, error) {
+	return []byte(formatInt(e.Value, e.Radix, e.Digits, e.GroupSize)), nil
+}
+
+// FloatExpression represents a floating-point literal. It is always printed
+// as a float, as in 1.0, NaN, Infinity and -Infinity.
 type FloatExpression float64
 
 func (e FloatExpression) isExpression() {}
 
 func (e FloatExpression) Marshal(_ context.Context) ([]byte, error) {
-	return []byte(strconv.FormatFloat(float64(e), 'f', -1, 64)), nil
+	return []byte(formatFloat(float64(e))), nil
 }
 
 // StringExpression represents a string literal.
@@ -60,8 +195,138 @@ type StringExpression string
 
 func (e StringExpression) isExpression() {}
 
-func (e StringExpression) Marshal(_ context.Context) ([]byte, error) {
-	return []byte(strconv.Quote(string(e))), nil
+func (e StringExpression) Marshal(ctx context.Context) ([]byte, error) {
+	return []byte(quoteString(ctx, string(e))), nil
+}
+
+// RawStringExpression is a string literal with custom delimiters, such as
+// #"C:\path"#, in which quotes and backslashes need no escaping.
+type RawStringExpression struct {
+	// Required.
+	Value string
+	// Number of # characters of the delimiters. It is raised to the minimum
+	// needed for the value not to end the string.
+	Pounds int
+
+	Span
+}
+
+func (e *RawStringExpression) isExpression() {}
+
+func (e *RawStringExpression) Marshal(_ context.Context) ([]byte, error) {
+	return []byte(quote(e.Value, max(e.Pounds, delimiterPounds(e.Value)))), nil
+}
+
+// MultiLineStringExpression is a multi-line string literal, delimited by """.
+// Its lines are indented one level deeper than the current indentation level,
+// as is the closing delimiter.
+type MultiLineStringExpression struct {
+	// Required.
+	Value string
+	// Number of # characters of the custom delimiters, if any. It is raised to
+	// the minimum needed for the value not to end the string.
+	Pounds int
+
+	Span
+}
+
+func (e *MultiLineStringExpression) isExpression() {}
+
+func (e *MultiLineStringExpression) Marshal(ctx context.Context) ([]byte, error) {
+	return []byte(quoteMultilineString(ctx, e.Value, e.Pounds)), nil
+}
+
+// InterpolatedStringExpression is a string literal with interpolated
+// expressions, such as "Hello, \(name)!".
+type InterpolatedStringExpression struct {
+	// Required. The text and the interpolated expressions, in order.
+	Parts StringParts
+	// Number of # characters of the custom delimiters, if any, in which case
+	// the expressions are interpolated with \#(...). It is raised to the
+	// minimum needed for the text not to end the string.
+	Pounds int
+	// Whether the string is a multi-line string, delimited by """, whose
+	// lines are indented as in MultiLineStringExpression.
+	MultiLine bool
+
+	Span
+}
+
+// StringPart is a part of an InterpolatedStringExpression: either a
+// StringText or a StringInterpolation.
+type StringPart interface {
+	isStringPart()
+}
+
+type StringParts []StringPart
+
+// StringText is text of an interpolated string, escaped when printed.
+type StringText string
+
+func (t StringText) isStringPart() {}
+
+// StringInterpolation is an expression interpolated in a string.
+type StringInterpolation struct {
+	// Required.
+	Expression Expression
+
+	Span
+}
+
+func (i *StringInterpolation) isStringPart() {}
+
+func (e *InterpolatedStringExpression) isExpression() {}
+
+func (e *InterpolatedStringExpression) Marshal(ctx context.Context) ([]byte, error) {
+	var texts []string
+	for _, part := range e.Parts {
+		if text, ok := part.(StringText); ok {
+			texts = append(texts, string(text))
+		}
+	}
+	pounds := stringPounds(ctx, texts, e.Pounds, e.MultiLine)
+
+	indent := ""
+	if e.MultiLine {
+		ctx = raiseIndentLevel(ctx)
+		indent = indentation(ctx)
+	}
+	ctx = withoutWrapping(ctx)
+
+	w := newStringWriter(pounds, e.MultiLine, indent)
+	for i, part := range e.Parts {
+		switch part := part.(type) {
+		case nil:
+			return nil, requiredError(e, index("Parts", i))
+		case StringText:
+			w.writeText(string(part))
+		case *StringInterpolation:
+			if part == nil {
+				return nil, requiredError(e, index("Parts", i))
+			}
+			expr, err := marshalInterpolation(ctx, part)
+			if err != nil {
+				return nil, fieldError(e, index("Parts", i), err)
+			}
+			w.writeInterpolation(expr)
+		}
+	}
+
+	return []byte(w.close()), nil
+}
+
+// marshalInterpolation returns the source code of the expression interpolated
+// in a string.
+func marshalInterpolation(ctx context.Context, i *StringInterpolation) ([]byte, error) {
+	if isNil(i.Expression) {
+		return nil, requiredError(i, "Expression")
+	}
+
+	expr, err := i.Expression.Marshal(ctx)
+	if err != nil {
+		return nil, fieldError(i, "Expression", err)
+	}
+	return expr, nil
 }
 
 type PrefixUnaryExpression struct {
@@ -69,6 +334,8 @@ type PrefixUnaryExpression struct {
 	Operator PrefixUnaryOperand
 	// Required.
 	Operand Expression
+
+	Span
 }
 
 func (e *PrefixUnaryExpression) isExpression() {}
@@ -76,10 +343,15 @@ func (e *PrefixUnaryExpression) isExpression() {}
 func (e *PrefixUnaryExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	operand, err := e.Operand.Marshal(ctx)
+	operand, err := marshalOperand(ctx, e, "Operand", e.Operand, precPrefix, true)
 	if err != nil {
 		return nil, err
 	}
+	// !! is the non-null assertion operator, so a negated negation keeps its
+	// parentheses.
+	if e.Operator == UnaryOperandLogicalNot && bytes.HasPrefix(operand, []byte("!")) {
+		operand = parenthesize(operand)
+	}
 
 	b.WriteString(string(e.Operator))
 	b.Write(operand)
@@ -92,6 +364,8 @@ type PostfixUnaryExpression struct {
 	Operator PostfixUnaryOperand
 	// Required.
 	Operand Expression
+
+	Span
 }
 
 func (e *PostfixUnaryExpression) isExpression() {}
@@ -99,7 +373,7 @@ func (e *PostfixUnaryExpression) isExpression() {}
 func (e *PostfixUnaryExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	operand, err := e.Operand.Marshal(ctx)
+	operand, err := marshalOperand(ctx, e, "Operand", e.Operand, precPostfix, false)
 	if err != nil {
 		return nil, err
 	}
@@ -117,28 +391,60 @@ type BinaryExpression struct {
 	Left Expression
 	// Required.
 	Right Expression
+
+	Span
 }
 
 func (e *BinaryExpression) isExpression() {}
 
 func (e *BinaryExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-
-	left, err := e.Left.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	ctx, b := newDocBuffer(ctx)
+
+	// A chain of operations is wrapped as a whole, after each operator.
+	// The paths are the paths to the operations in the chain from e.
+	chain, paths := []*BinaryExpression{e}, []string{""}
+	for {
+		path, operand := unparenthesize(ctx, field(paths[0], "Left"), chain[0].Left)
+		left, ok := operand.(*BinaryExpression)
+		if !ok || left == nil {
+			break
+		}
+		if prec, _ := chain[0].Operator.OperandPrecedences(); left.Operator.Precedence() < prec {
+			break
+		}
+		chain = append([]*BinaryExpression{left}, chain...)
+		paths = append([]string{path}, paths...)
 	}
 
-	right, err := e.Right.Marshal(ctx)
+	leftPrec, _ := chain[0].Operator.OperandPrecedences()
+	left, err := marshalOperand(ctx, chain[0], "Left", chain[0].Left, leftPrec, false)
 	if err != nil {
-		return nil, err
+		return nil, prefixPath(paths[0], err)
 	}
 
+	b.StartGroup()
 	b.WriteWithSuffix(left, " ")
-	b.WriteString(string(e.Operator))
-	b.WriteWithPrefix(" ", right)
+	b.Indent()
+	for i, op := range chain {
+		_, rightPrec := op.Operator.OperandPrecedences()
+		right, err := marshalOperand(ctx, op, "Right", op.Right, rightPrec, i == len(chain)-1)
+		if err != nil {
+			return nil, prefixPath(paths[i], err)
+		}
 
-	return b.Bytes(), nil
+		if i > 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteString(string(op.Operator))
+		if len(right) > 0 {
+			b.Line()
+			b.Write(right)
+		}
+	}
+	b.Dedent()
+	b.EndGroup()
+
+	return b.Doc(), nil
 }
 
 type TypeExpression struct {
@@ -148,6 +454,8 @@ type TypeExpression struct {
 	Expression Expression
 	// Required.
 	Type Type
+
+	Span
 }
 
 func (e *TypeExpression) isExpression() {}
@@ -155,12 +463,12 @@ func (e *TypeExpression) isExpression() {}
 func (e *TypeExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	expr, err := e.Expression.Marshal(ctx)
+	expr, err := marshalOperand(ctx, e, "Expression", e.Expression, precTypeTest, false)
 	if err != nil {
 		return nil, err
 	}
 
-	typ, err := e.Type.Marshal(ctx)
+	typ, err := marshalField(ctx, e, "Type", e.Type)
 	if err != nil {
 		return nil, err
 	}
@@ -177,30 +485,26 @@ type MemberAccessExpression struct {
 	Name Identifier
 	// Optional.
 	Arguments Expressions
+
+	Span
 }
 
 func (e *MemberAccessExpression) isExpression() {}
 
 func (e *MemberAccessExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
 
-	name, err := e.Name.Marshal(ctx)
+	name, err := marshalField(ctx, e, "Name", e.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(name)
 
-	if e.Arguments != nil {
-		args, err := e.Arguments.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteRune('(')
-		b.Write(args)
-		b.WriteRune(')')
+	if err := b.writeArguments(e, "Arguments", e.Arguments); err != nil {
+		return nil, err
 	}
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 type QualifiedMemberAccessExpression struct {
@@ -212,41 +516,71 @@ type QualifiedMemberAccessExpression struct {
 	Name Identifier
 	// Optional.
 	Arguments Expressions
+
+	Span
 }
 
 func (e *QualifiedMemberAccessExpression) isExpression() {}
 
 func (e *QualifiedMemberAccessExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
+
+	// A chain of method calls is wrapped as a whole, before each access.
+	// The paths are the paths to the accesses in the chain from e.
+	chain, paths := []*QualifiedMemberAccessExpression{e}, []string{""}
+	calls := 0
+	for {
+		if chain[0].Arguments != nil {
+			calls++
+		}
+		receiver, ok := chain[0].Receiver.(*QualifiedMemberAccessExpression)
+		if !ok || receiver == nil {
+			break
+		}
+		chain = append([]*QualifiedMemberAccessExpression{receiver}, chain...)
+		paths = append([]string{field(paths[0], "Receiver")}, paths...)
+	}
+	if calls < 2 {
+		chain, paths = chain[len(chain)-1:], paths[len(paths)-1:]
+	}
 
-	receiver, err := e.Receiver.Marshal(ctx)
+	receiver, err := marshalOperand(ctx, chain[0], "Receiver", chain[0].Receiver, precPrimary, false)
 	if err != nil {
-		return nil, err
+		return nil, prefixPath(paths[0], err)
 	}
 	b.Write(receiver)
-	if e.Nullable {
-		b.WriteString(".?")
-	} else {
-		b.WriteRune('.')
-	}
 
-	name, err := e.Name.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	wrap := len(chain) > 1
+	if wrap {
+		b.StartGroup()
+		b.Indent()
 	}
-	b.Write(name)
+	for i, access := range chain {
+		if wrap {
+			b.SoftLine()
+		}
+		if access.Nullable {
+			b.WriteString("?.")
+		} else {
+			b.WriteRune('.')
+		}
 
-	if e.Arguments != nil {
-		args, err := e.Arguments.Marshal(ctx)
+		name, err := marshalField(ctx, access, "Name", access.Name)
 		if err != nil {
-			return nil, err
+			return nil, prefixPath(paths[i], err)
+		}
+		b.Write(name)
+
+		if err := b.writeArguments(access, "Arguments", access.Arguments); err != nil {
+			return nil, prefixPath(paths[i], err)
 		}
-		b.WriteRune('(')
-		b.Write(args)
-		b.WriteRune(')')
+	}
+	if wrap {
+		b.Dedent()
+		b.EndGroup()
 	}
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 type SuperAccessExpression struct {
@@ -254,31 +588,26 @@ type SuperAccessExpression struct {
 	Name Identifier
 	// Optional.
 	Arguments Expressions
+
+	Span
 }
 
 func (e *SuperAccessExpression) isExpression() {}
 
 func (e *SuperAccessExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
 
-	name, err := e.Name.Marshal(ctx)
+	name, err := marshalField(ctx, e, "Name", e.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix("super.", name)
 
-	if e.Arguments != nil {
-		args, err := e.Arguments.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-
-		b.WriteRune('(')
-		b.Write(args)
-		b.WriteRune(')')
+	if err := b.writeArguments(e, "Arguments", e.Arguments); err != nil {
+		return nil, err
 	}
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 type SubscriptExpression struct {
@@ -286,6 +615,8 @@ type SubscriptExpression struct {
 	Receiver Expression
 	// Required.
 	Subscript Expression
+
+	Span
 }
 
 func (e *SubscriptExpression) isExpression() {}
@@ -293,13 +624,13 @@ func (e *SubscriptExpression) isExpression() {}
 func (e *SubscriptExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	receiver, err := e.Receiver.Marshal(ctx)
+	receiver, err := marshalOperand(ctx, e, "Receiver", e.Receiver, precPrimary, false)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(receiver)
 
-	subscript, err := e.Subscript.Marshal(ctx)
+	subscript, err := marshalField(ctx, e, "Subscript", e.Subscript)
 	if err != nil {
 		return nil, err
 	}
@@ -311,6 +642,8 @@ func (e *SubscriptExpression) Marshal(ctx context.Context) ([]byte, error) {
 type SuperSubscriptExpression struct {
 	// Required.
 	Subscript Expression
+
+	Span
 }
 
 func (e *SuperSubscriptExpression) isExpression() {}
@@ -318,7 +651,7 @@ func (e *SuperSubscriptExpression) isExpression() {}
 func (e *SuperSubscriptExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	subscript, err := e.Subscript.Marshal(ctx)
+	subscript, err := marshalField(ctx, e, "Subscript", e.Subscript)
 	if err != nil {
 		return nil, err
 	}
@@ -330,21 +663,27 @@ func (e *SuperSubscriptExpression) Marshal(ctx context.Context) ([]byte, error)
 type ParenthesizedExpression struct {
 	// Required.
 	Expression Expression
+
+	Span
 }
 
 func (e *ParenthesizedExpression) isExpression()            {}
 func (e *ParenthesizedExpression) isAmendParentExpression() {}
 
 func (e *ParenthesizedExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	// The operands that need parentheses get them from their operator.
+	if getPrintConfig(ctx).StripParentheses {
+		return marshalField(ctx, e, "Expression", e.Expression)
+	}
+	return e.marshalParenthesized(ctx)
+}
 
-	expr, err := e.Expression.Marshal(ctx)
+func (e *ParenthesizedExpression) marshalParenthesized(ctx context.Context) ([]byte, error) {
+	expr, err := marshalField(ctx, e, "Expression", e.Expression)
 	if err != nil {
 		return nil, err
 	}
-	b.WriteWithPrefixSuffix("(", expr, ")")
-
-	return b.Bytes(), nil
+	return parenthesize(expr), nil
 }
 
 type NewExpression struct {
@@ -352,31 +691,25 @@ type NewExpression struct {
 	Type Type
 	// Required.
 	Body *ObjectBody
+
+	Span
 }
 
 func (e *NewExpression) isExpression()            {}
 func (e *NewExpression) isAmendParentExpression() {}
 
 func (e *NewExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	return marshalNode(ctx, e)
+}
 
+func (e *NewExpression) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
 	b.WriteString("new ")
 
-	if e.Type != nil {
-		typ, err := e.Type.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithSuffix(typ, " ")
-	}
-
-	body, err := e.Body.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", e, "Type", e.Type, " "); err != nil {
+		return err
 	}
-	b.Write(body)
 
-	return b.Bytes(), nil
+	return writeField(ctx, b, e, "Body", e.Body)
 }
 
 type AmendParentExpression interface {
@@ -389,28 +722,32 @@ type AmendExpression struct {
 	Parent AmendParentExpression
 	// Required.
 	Body *ObjectBody
+
+	Span
 }
 
 func (e *AmendExpression) isExpression()            {}
 func (e *AmendExpression) isAmendParentExpression() {}
 
 func (e *AmendExpression) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	return marshalNode(ctx, e)
+}
 
-	parent, err := e.Parent.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	body, err := e.Body.Marshal(ctx)
-	if err != nil {
-		return nil, err
+func (e *AmendExpression) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	// The parentheses of an amended expression are never redundant.
+	if paren, ok := e.Parent.(*ParenthesizedExpression); ok && paren != nil {
+		parent, err := paren.marshalParenthesized(ctx)
+		if err != nil {
+			return fieldError(e, "Parent", err)
+		}
+		b.Write(parent)
+	} else if err := writeField(ctx, b, e, "Parent", e.Parent); err != nil {
+		return err
 	}
 
-	b.Write(parent)
 	b.WriteRune(' ')
-	b.Write(body)
 
-	return b.Bytes(), nil
+	return writeField(ctx, b, e, "Body", e.Body)
 }
 
 type IfExpression struct {
@@ -420,6 +757,8 @@ type IfExpression struct {
 	Then Expression
 	// Required.
 	Else Expression
+
+	Span
 }
 
 func (e *IfExpression) isExpression() {}
@@ -427,19 +766,19 @@ func (e *IfExpression) isExpression() {}
 func (e *IfExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	cond, err := e.Condition.Marshal(ctx)
+	cond, err := marshalField(ctx, e, "Condition", e.Condition)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix("if (", cond, ") ")
 
-	then, err := e.Then.Marshal(ctx)
+	then, err := marshalField(ctx, e, "Then", e.Then)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(then)
 
-	elseExpr, err := e.Else.Marshal(ctx)
+	elseExpr, err := marshalField(ctx, e, "Else", e.Else)
 	if err != nil {
 		return nil, err
 	}
@@ -453,6 +792,8 @@ type ImportExpression struct {
 	Path string
 	// Optional.
 	Glob bool
+
+	Span
 }
 
 func (e *ImportExpression) isExpression() {}
@@ -465,7 +806,7 @@ func (e *ImportExpression) Marshal(ctx context.Context) ([]byte, error) {
 	} else {
 		b.WriteString("import(")
 	}
-	b.WriteString(strconv.Quote(e.Path))
+	b.WriteString(quote(e.Path, 0))
 	b.WriteRune(')')
 
 	return b.Bytes(), nil
@@ -478,6 +819,8 @@ type LetExpression struct {
 	Value Expression
 	// Required.
 	Expression Expression
+
+	Span
 }
 
 func (e *LetExpression) isExpression() {}
@@ -485,19 +828,19 @@ func (e *LetExpression) isExpression() {}
 func (e *LetExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	name, err := e.Name.Marshal(ctx)
+	name, err := marshalField(ctx, e, "Name", e.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix("let (", name, " = ")
 
-	val, err := e.Value.Marshal(ctx)
+	val, err := marshalField(ctx, e, "Value", e.Value)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(val, ") ")
 
-	expr, err := e.Expression.Marshal(ctx)
+	expr, err := marshalField(ctx, e, "Expression", e.Expression)
 	if err != nil {
 		return nil, err
 	}
@@ -506,6 +849,38 @@ func (e *LetExpression) Marshal(ctx context.Context) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// FunctionLiteralExpression is an anonymous function, such as (x) -> x * 2.
+type FunctionLiteralExpression struct {
+	// Optional. The parameters may be typed.
+	Parameters Parameters
+	// Required.
+	Body Expression
+
+	Span
+}
+
+func (e *FunctionLiteralExpression) isExpression() {}
+
+func (e *FunctionLiteralExpression) Marshal(ctx context.Context) ([]byte, error) {
+	var b bytesutil.Buffer
+
+	params, err := marshalField(ctx, e, "Parameters", e.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteRune('(')
+	b.Write(params)
+	b.WriteRune(')')
+
+	body, err := marshalField(ctx, e, "Body", e.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteWithPrefix(" -> ", body)
+
+	return b.Bytes(), nil
+}
+
 type ReadVariant string
 
 const (
@@ -518,6 +893,8 @@ type ReadExpression struct {
 	Variant ReadVariant
 	// Required.
 	Value Expression
+
+	Span
 }
 
 func (e *ReadExpression) isExpression() {}
@@ -534,7 +911,7 @@ func (e *ReadExpression) Marshal(ctx context.Context) ([]byte, error) {
 		b.WriteString("read(")
 	}
 
-	expr, err := e.Value.Marshal(ctx)
+	expr, err := marshalField(ctx, e, "Value", e.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -547,6 +924,8 @@ func (e *ReadExpression) Marshal(ctx context.Context) ([]byte, error) {
 type ThrowExpression struct {
 	// Required.
 	Value Expression
+
+	Span
 }
 
 func (e *ThrowExpression) isExpression() {}
@@ -554,7 +933,7 @@ func (e *ThrowExpression) isExpression() {}
 func (e *ThrowExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	expr, err := e.Value.Marshal(ctx)
+	expr, err := marshalField(ctx, e, "Value", e.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -566,6 +945,8 @@ func (e *ThrowExpression) Marshal(ctx context.Context) ([]byte, error) {
 type TraceExpression struct {
 	// Required.
 	Value Expression
+
+	Span
 }
 
 func (e *TraceExpression) isExpression() {}
@@ -573,7 +954,7 @@ func (e *TraceExpression) isExpression() {}
 func (e *TraceExpression) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	expr, err := e.Value.Marshal(ctx)
+	expr, err := marshalField(ctx, e, "Value", e.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"context"
+	"math"
 	"testing"
 
 	"github.com/pauloborges/balsamic/internal/stringsutil"
@@ -101,7 +102,7 @@ func TestFloatExpressionMarshal(t *testing.T) {
 		{
 			name: "zero",
 			node: 0.0,
-			res:  "0",
+			res:  "0.0",
 		},
 		{
 			name: "positive",
@@ -113,6 +114,31 @@ func TestFloatExpressionMarshal(t *testing.T) {
 			node: -3.1415,
 			res:  "-3.1415",
 		},
+		{
+			name: "integral",
+			node: 1,
+			res:  "1.0",
+		},
+		{
+			name: "large",
+			node: 1e300,
+			res:  "1e+300",
+		},
+		{
+			name: "not a number",
+			node: FloatExpression(math.NaN()),
+			res:  "NaN",
+		},
+		{
+			name: "infinity",
+			node: FloatExpression(math.Inf(1)),
+			res:  "Infinity",
+		},
+		{
+			name: "negative infinity",
+			node: FloatExpression(math.Inf(-1)),
+			res:  "-Infinity",
+		},
 	}
 
 	for _, test := range tests {
@@ -125,12 +151,60 @@ func TestFloatExpressionMarshal(t *testing.T) {
 	}
 }
 
-func TestStringExpressionMarshal(t *testing.T) {
+func TestFormattedIntExpressionMarshal(t *testing.T) {
 	tests := []struct {
 		name string
-		node StringExpression
+		node Expression
 		res  string
 		err  error
+	}{
+		{
+			name: "decimal",
+			node: &FormattedIntExpression{Value: 8080},
+			res:  "8080",
+		},
+		{
+			name: "hexadecimal",
+			node: &FormattedIntExpression{Value: 0xFF, Radix: IntRadixHexadecimal, Digits: 4},
+			res:  "0x00FF",
+		},
+		{
+			name: "octal",
+			node: &FormattedIntExpression{Value: 0o755, Radix: IntRadixOctal},
+			res:  "0o755",
+		},
+		{
+			name: "grouped",
+			node: &FormattedIntExpression{Value: 1 << 30, GroupSize: 3},
+			res:  "1_073_741_824",
+		},
+		{
+			name: "negative receiver",
+			node: &QualifiedMemberAccessExpression{
+				Receiver: &FormattedIntExpression{Value: -0b101, Radix: IntRadixBinary},
+				Name:     Identifier("abs"),
+			},
+			res: "(-0b101).abs",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.node.Marshal(context.Background())
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
+func TestStringExpressionMarshal(t *testing.T) {
+	tests := []struct {
+		name             string
+		customDelimiters bool
+		node             StringExpression
+		res              string
+		err              error
 	}{
 		{
 			name: "empty",
@@ -147,6 +221,61 @@ func TestStringExpressionMarshal(t *testing.T) {
 			node: `"hello"`,
 			res:  `"\"hello\""`,
 		},
+		{
+			name:             "custom delimiters",
+			customDelimiters: true,
+			node:             `^\d+"#$`,
+			res:              `##"^\d+"#$"##`,
+		},
+		{
+			name:             "custom delimiters not needed",
+			customDelimiters: true,
+			node:             "hello",
+			res:              `"hello"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultPrintConfig
+			config.CustomDelimiters = test.customDelimiters
+			ctx := WithPrintConfig(context.Background(), config)
+
+			res, err := test.node.Marshal(ctx)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
+func TestRawStringExpressionMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		node RawStringExpression
+		res  string
+		err  error
+	}{
+		{
+			name: "minimal delimiters",
+			node: RawStringExpression{Value: `C:\path`},
+			res:  `#"C:\path"#`,
+		},
+		{
+			name: "explicit delimiters",
+			node: RawStringExpression{Value: `C:\path`, Pounds: 2},
+			res:  `##"C:\path"##`,
+		},
+		{
+			name: "delimiters raised",
+			node: RawStringExpression{Value: `say "#hi"`, Pounds: 1},
+			res:  `##"say "#hi""##`,
+		},
+		{
+			name: "escapes",
+			node: RawStringExpression{Value: "a\tb"},
+			res:  `#"a\#tb"#`,
+		},
 	}
 
 	for _, test := range tests {
@@ -159,6 +288,201 @@ func TestStringExpressionMarshal(t *testing.T) {
 	}
 }
 
+func TestMultiLineStringExpressionMarshal(t *testing.T) {
+	tests := []struct {
+		name             string
+		customDelimiters bool
+		node             Node
+		res              string
+		err              error
+	}{
+		{
+			name: "multi-line",
+			node: &MultiLineStringExpression{Value: "#!/bin/sh\necho \"hello\""},
+			res: stringsutil.StripMargin(`
+				|"""
+				|  #!/bin/sh
+				|  echo "hello"
+				|  """
+			`),
+		},
+		{
+			name: "explicit delimiters",
+			node: &MultiLineStringExpression{Value: `\d+`, Pounds: 1},
+			res: stringsutil.StripMargin(`
+				|#"""
+				|  \d+
+				|  """#
+			`),
+		},
+		{
+			name:             "custom delimiters",
+			customDelimiters: true,
+			node:             &MultiLineStringExpression{Value: `say """hi"""`},
+			res: stringsutil.StripMargin(`
+				|#"""
+				|  say """hi"""
+				|  """#
+			`),
+		},
+		{
+			name: "indented",
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectProperty{
+						Name:  Identifier("script"),
+						Value: &MultiLineStringExpression{Value: "set -e\n\nexit 0"},
+					},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|{
+				|  script = """
+				|    set -e
+				|
+				|    exit 0
+				|    """
+				|}
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultPrintConfig
+			config.CustomDelimiters = test.customDelimiters
+			ctx := WithPrintConfig(context.Background(), config)
+
+			res, err := test.node.Marshal(ctx)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
+func TestInterpolatedStringExpressionMarshal(t *testing.T) {
+	tests := []struct {
+		name             string
+		customDelimiters bool
+		maxWidth         uint
+		node             Node
+		res              string
+		err              error
+	}{
+		{
+			name: "interpolation",
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					StringText("Hello, "),
+					&StringInterpolation{Expression: &MemberAccessExpression{Name: "name"}},
+					StringText("!\n"),
+				},
+			},
+			res: `"Hello, \(name)!\n"`,
+		},
+		{
+			name: "nested",
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					&StringInterpolation{Expression: &MemberAccessExpression{
+						Name: "upper",
+						Arguments: Expressions{
+							&InterpolatedStringExpression{
+								Parts: StringParts{
+									StringText(`"`),
+									&StringInterpolation{Expression: &MemberAccessExpression{Name: "x"}},
+									StringText(`"`),
+								},
+							},
+						},
+					}},
+				},
+			},
+			res: `"\(upper("\"\(x)\""))"`,
+		},
+		{
+			name: "explicit delimiters",
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					StringText(`\d+ `),
+					&StringInterpolation{Expression: &MemberAccessExpression{Name: "suffix"}},
+				},
+				Pounds: 1,
+			},
+			res: `#"\d+ \#(suffix)"#`,
+		},
+		{
+			name:             "custom delimiters",
+			customDelimiters: true,
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					StringText(`say "#`),
+					&StringInterpolation{Expression: &MemberAccessExpression{Name: "name"}},
+					StringText(`"`),
+				},
+			},
+			res: `##"say "#\##(name)""##`,
+		},
+		{
+			name: "multi-line",
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectProperty{
+						Name: Identifier("script"),
+						Value: &InterpolatedStringExpression{
+							Parts: StringParts{
+								StringText("cd "),
+								&StringInterpolation{Expression: &MemberAccessExpression{Name: "dir"}},
+								StringText("\n\n"),
+								&StringInterpolation{Expression: &MemberAccessExpression{Name: "command"}},
+								StringText(` """`),
+							},
+							MultiLine: true,
+						},
+					},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|{
+				|  script = """
+				|    cd \(dir)
+				|
+				|    \(command) ""\"
+				|    """
+				|}
+			`),
+		},
+		{
+			name:     "not wrapped",
+			maxWidth: 10,
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					&StringInterpolation{Expression: &MemberAccessExpression{
+						Name:      "max",
+						Arguments: Expressions{&MemberAccessExpression{Name: "first"}, &MemberAccessExpression{Name: "second"}},
+					}},
+				},
+			},
+			res: `"\(max(first, second))"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultPrintConfig
+			config.CustomDelimiters = test.customDelimiters
+			config.MaxWidth = test.maxWidth
+			ctx := WithPrintConfig(context.Background(), config)
+
+			res, err := test.node.Marshal(ctx)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
 func TestPrefixUnaryExpressionMarshal(t *testing.T) {
 	tests := []struct {
 		name string
@@ -182,6 +506,40 @@ func TestPrefixUnaryExpressionMarshal(t *testing.T) {
 			},
 			res: "-42",
 		},
+		{
+			name: "minus of addition",
+			node: PrefixUnaryExpression{
+				Operator: UnaryOperandMinus,
+				Operand: &BinaryExpression{
+					Operator: BinaryOperatorPlus,
+					Left:     IntExpression(2),
+					Right:    IntExpression(1),
+				},
+			},
+			res: "-(2 + 1)",
+		},
+		{
+			name: "not of not",
+			node: PrefixUnaryExpression{
+				Operator: UnaryOperandLogicalNot,
+				Operand: &PrefixUnaryExpression{
+					Operator: UnaryOperandLogicalNot,
+					Operand:  &MemberAccessExpression{Name: "a"},
+				},
+			},
+			res: "!(!a)",
+		},
+		{
+			name: "minus of minus",
+			node: PrefixUnaryExpression{
+				Operator: UnaryOperandMinus,
+				Operand: &PrefixUnaryExpression{
+					Operator: UnaryOperandMinus,
+					Operand:  &MemberAccessExpression{Name: "a"},
+				},
+			},
+			res: "--a",
+		},
 	}
 
 	for _, test := range tests {
@@ -209,6 +567,18 @@ func TestPostfixUnaryExpressionMarshal(t *testing.T) {
 			},
 			res: "foobar!!",
 		},
+		{
+			name: "non-null assertion of null coalesce",
+			node: PostfixUnaryExpression{
+				Operator: PostfixUnaryOperandNonNullAssertion,
+				Operand: &BinaryExpression{
+					Operator: BinaryOperatorNullCoalesce,
+					Left:     &MemberAccessExpression{Name: "foo"},
+					Right:    &MemberAccessExpression{Name: "bar"},
+				},
+			},
+			res: "(foo ?? bar)!!",
+		},
 	}
 
 	for _, test := range tests {
@@ -399,6 +769,105 @@ func TestBinaryExpressionMarshal(t *testing.T) {
 			},
 			res: "2 |> 1",
 		},
+		{
+			name: "lower precedence on the left",
+			node: BinaryExpression{
+				Operator: BinaryOperatorMultiply,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorPlus,
+					Left:     &MemberAccessExpression{Name: "a"},
+					Right:    &MemberAccessExpression{Name: "b"},
+				},
+				Right: &MemberAccessExpression{Name: "c"},
+			},
+			res: "(a + b) * c",
+		},
+		{
+			name: "higher precedence on the right",
+			node: BinaryExpression{
+				Operator: BinaryOperatorPlus,
+				Left:     &MemberAccessExpression{Name: "a"},
+				Right: &BinaryExpression{
+					Operator: BinaryOperatorMultiply,
+					Left:     &MemberAccessExpression{Name: "b"},
+					Right:    &MemberAccessExpression{Name: "c"},
+				},
+			},
+			res: "a + b * c",
+		},
+		{
+			name: "left associativity",
+			node: BinaryExpression{
+				Operator: BinaryOperatorMinus,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorMinus,
+					Left:     &MemberAccessExpression{Name: "a"},
+					Right:    &MemberAccessExpression{Name: "b"},
+				},
+				Right: &BinaryExpression{
+					Operator: BinaryOperatorMinus,
+					Left:     &MemberAccessExpression{Name: "c"},
+					Right:    &MemberAccessExpression{Name: "d"},
+				},
+			},
+			res: "a - b - (c - d)",
+		},
+		{
+			name: "right associativity",
+			node: BinaryExpression{
+				Operator: BinaryOperatorExponent,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorExponent,
+					Left:     &MemberAccessExpression{Name: "a"},
+					Right:    &MemberAccessExpression{Name: "b"},
+				},
+				Right: &BinaryExpression{
+					Operator: BinaryOperatorExponent,
+					Left:     &MemberAccessExpression{Name: "c"},
+					Right:    &MemberAccessExpression{Name: "d"},
+				},
+			},
+			res: "(a ** b) ** c ** d",
+		},
+		{
+			name: "null coalesce and pipe",
+			node: BinaryExpression{
+				Operator: BinaryOperatorPipe,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorNullCoalesce,
+					Left:     &MemberAccessExpression{Name: "a"},
+					Right:    &MemberAccessExpression{Name: "b"},
+				},
+				Right: &MemberAccessExpression{Name: "f"},
+			},
+			res: "(a ?? b) |> f",
+		},
+		{
+			name: "type test operand",
+			node: BinaryExpression{
+				Operator: BinaryOperatorEqual,
+				Left: &TypeExpression{
+					Operator:   TypeOperatorAs,
+					Expression: &MemberAccessExpression{Name: "a"},
+					Type:       &DeclaredType{Name: "Int"},
+				},
+				Right: IntExpression(1),
+			},
+			res: "a as Int == 1",
+		},
+		{
+			name: "if expression operand",
+			node: BinaryExpression{
+				Operator: BinaryOperatorPlus,
+				Left: &IfExpression{
+					Condition: &MemberAccessExpression{Name: "a"},
+					Then:      IntExpression(1),
+					Else:      IntExpression(2),
+				},
+				Right: IntExpression(3),
+			},
+			res: "(if (a) 1 else 2) + 3",
+		},
 	}
 
 	for _, test := range tests {
@@ -436,6 +905,19 @@ func TestTypeExpressionMarshal(t *testing.T) {
 			},
 			res: `"foobar" as String`,
 		},
+		{
+			name: "null coalesce operand",
+			node: TypeExpression{
+				Operator: TypeOperatorIs,
+				Expression: &BinaryExpression{
+					Operator: BinaryOperatorNullCoalesce,
+					Left:     &MemberAccessExpression{Name: "foo"},
+					Right:    StringExpression("bar"),
+				},
+				Type: &DeclaredType{Name: "String"},
+			},
+			res: `(foo ?? "bar") is String`,
+		},
 	}
 
 	for _, test := range tests {
@@ -507,7 +989,7 @@ func TestQualifiedMemberAccessExpressionMarshal(t *testing.T) {
 				Name:     "foobar",
 				Nullable: true,
 			},
-			res: "this.?foobar",
+			res: "this?.foobar",
 		},
 		{
 			name: "call without arguments",
@@ -535,7 +1017,7 @@ func TestQualifiedMemberAccessExpressionMarshal(t *testing.T) {
 				Arguments: NoArguments,
 				Nullable:  true,
 			},
-			res: "this.?foobar()",
+			res: "this?.foobar()",
 		},
 	}
 
@@ -649,23 +1131,66 @@ func TestSuperSubscriptExpressionMarshal(t *testing.T) {
 
 func TestParenthesizedExpressionMarshal(t *testing.T) {
 	tests := []struct {
-		name string
-		node ParenthesizedExpression
-		res  string
-		err  error
+		name  string
+		strip bool
+		node  Expression
+		res   string
+		err   error
 	}{
 		{
 			name: "simple",
-			node: ParenthesizedExpression{
+			node: &ParenthesizedExpression{
 				Expression: IntExpression(42),
 			},
 			res: "(42)",
 		},
+		{
+			name:  "stripped",
+			strip: true,
+			node: &ParenthesizedExpression{
+				Expression: &ParenthesizedExpression{Expression: IntExpression(42)},
+			},
+			res: "42",
+		},
+		{
+			name:  "stripped operands",
+			strip: true,
+			node: &BinaryExpression{
+				Operator: BinaryOperatorMultiply,
+				Left: &ParenthesizedExpression{
+					Expression: &BinaryExpression{
+						Operator: BinaryOperatorPlus,
+						Left:     &MemberAccessExpression{Name: "a"},
+						Right:    &MemberAccessExpression{Name: "b"},
+					},
+				},
+				Right: &ParenthesizedExpression{
+					Expression: &QualifiedMemberAccessExpression{
+						Receiver: &ParenthesizedExpression{Expression: &MemberAccessExpression{Name: "c"}},
+						Name:     "d",
+					},
+				},
+			},
+			res: "(a + b) * c.d",
+		},
+		{
+			name:  "amended expression",
+			strip: true,
+			node: &AmendExpression{
+				Parent: &ParenthesizedExpression{Expression: &MemberAccessExpression{Name: "foo"}},
+				Body:   &ObjectBody{},
+			},
+			res: "(foo) {}",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			res, err := test.node.Marshal(context.Background())
+			config := DefaultPrintConfig
+			config.StripParentheses = test.strip
+			ctx := WithPrintConfig(context.Background(), config)
+
+			res, err := test.node.Marshal(ctx)
 
 			assert.Equal(t, test.err, err)
 			assert.Equal(t, test.res, string(res))
@@ -856,6 +1381,128 @@ func TestLetExpressionMarshal(t *testing.T) {
 	}
 }
 
+func TestFunctionLiteralExpressionMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		node Expression
+		res  string
+		err  error
+	}{
+		{
+			name: "untyped",
+			node: &FunctionLiteralExpression{
+				Parameters: Parameters{&Parameter{Name: Identifier("x")}},
+				Body: &BinaryExpression{
+					Operator: BinaryOperatorMultiply,
+					Left:     &MemberAccessExpression{Name: "x"},
+					Right:    IntExpression(2),
+				},
+			},
+			res: `(x) -> x * 2`,
+		},
+		{
+			name: "typed",
+			node: &FunctionLiteralExpression{
+				Parameters: Parameters{
+					&Parameter{Name: Identifier("a"), Type: &DeclaredType{Name: "Int"}},
+					&Parameter{Name: Identifier("b"), Type: &DeclaredType{Name: "Int"}},
+				},
+				Body: &BinaryExpression{
+					Operator: BinaryOperatorPlus,
+					Left:     &MemberAccessExpression{Name: "a"},
+					Right:    &MemberAccessExpression{Name: "b"},
+				},
+			},
+			res: `(a: Int, b: Int) -> a + b`,
+		},
+		{
+			name: "no parameters",
+			node: &FunctionLiteralExpression{Body: IntExpression(42)},
+			res:  `() -> 42`,
+		},
+		{
+			name: "argument",
+			node: &QualifiedMemberAccessExpression{
+				Receiver: &MemberAccessExpression{Name: "list"},
+				Name:     Identifier("map"),
+				Arguments: Expressions{
+					&FunctionLiteralExpression{
+						Parameters: Parameters{&Parameter{Name: Identifier("it")}},
+						Body: &QualifiedMemberAccessExpression{
+							Receiver: &MemberAccessExpression{Name: "it"},
+							Name:     Identifier("name"),
+						},
+					},
+				},
+			},
+			res: `list.map((it) -> it.name)`,
+		},
+		{
+			name: "pipe operand",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPipe,
+				Left:     &MemberAccessExpression{Name: "list"},
+				Right: &FunctionLiteralExpression{
+					Parameters: Parameters{&Parameter{Name: Identifier("it")}},
+					Body: &QualifiedMemberAccessExpression{
+						Receiver: &MemberAccessExpression{Name: "it"},
+						Name:     Identifier("length"),
+					},
+				},
+			},
+			res: `list |> (it) -> it.length`,
+		},
+		{
+			name: "left operand",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPipe,
+				Left: &FunctionLiteralExpression{
+					Parameters: Parameters{&Parameter{Name: Identifier("it")}},
+					Body:       &MemberAccessExpression{Name: "it"},
+				},
+				Right: &MemberAccessExpression{Name: "f"},
+			},
+			res: `((it) -> it) |> f`,
+		},
+		{
+			name: "operand ending with function literal",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPipe,
+				Left: &PrefixUnaryExpression{
+					Operator: UnaryOperandMinus,
+					Operand: &FunctionLiteralExpression{
+						Parameters: Parameters{&Parameter{Name: Identifier("it")}},
+						Body:       &MemberAccessExpression{Name: "it"},
+					},
+				},
+				Right: &MemberAccessExpression{Name: "f"},
+			},
+			res: `(-(it) -> it) |> f`,
+		},
+		{
+			name: "receiver",
+			node: &QualifiedMemberAccessExpression{
+				Receiver: &FunctionLiteralExpression{
+					Parameters: Parameters{&Parameter{Name: Identifier("x")}},
+					Body:       &MemberAccessExpression{Name: "x"},
+				},
+				Name:      Identifier("apply"),
+				Arguments: Expressions{IntExpression(1)},
+			},
+			res: `((x) -> x).apply(1)`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.node.Marshal(context.Background())
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
 func TestReadExpressionMarshal(t *testing.T) {
 	tests := []struct {
 		name string
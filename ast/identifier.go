@@ -2,18 +2,97 @@ package ast
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pauloborges/balsamic/token"
 )
 
 const IdentifierBlank Identifier = "_"
 
+// Identifier is the name of a declaration or member. If it is a keyword or
+// has characters not allowed in identifiers, it is printed between backticks,
+// as in `default-timeout`.
 type Identifier string
 
-func (i Identifier) Marshal(_ context.Context) ([]byte, error) {
-	return []byte(i), nil
+func (i Identifier) Marshal(ctx context.Context) ([]byte, error) {
+	return []byte(escapeDoc(ctx, quoteIdentifier(string(i)))), nil
 }
 
+// QualifiedIdentifier is a sequence of identifiers separated by dots. Each
+// of them is printed between backticks if needed, as Identifier is.
 type QualifiedIdentifier string
 
-func (i QualifiedIdentifier) Marshal(_ context.Context) ([]byte, error) {
-	return []byte(i), nil
+func (i QualifiedIdentifier) Marshal(ctx context.Context) ([]byte, error) {
+	parts := strings.Split(string(i), ".")
+	for j, part := range parts {
+		parts[j] = quoteIdentifier(part)
+	}
+	return []byte(escapeDoc(ctx, strings.Join(parts, "."))), nil
+}
+
+// IsPlainIdentifier reports whether s can be written as an identifier without
+// backticks: it starts with a letter, _ or $, has only letters, digits, _ and
+// $, and is not a keyword.
+func IsPlainIdentifier(s string) bool {
+	if s == "" || token.Lookup(s) != token.IDENT {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ValidateIdentifier reports whether s can be written as an identifier, at
+// least between backticks. It returns an error if s is empty, or has
+// backticks or line breaks, which can not be quoted.
+func ValidateIdentifier(s string) error {
+	if msg := identifierError(s); msg != "" {
+		return errors.New("ast: " + msg)
+	}
+	return nil
+}
+
+// identifierError returns why s can not be written as an identifier, if so.
+func identifierError(s string) string {
+	switch {
+	case s == "":
+		return "empty identifier"
+	case strings.ContainsAny(s, "`\r\n"):
+		return "identifier " + quote(s, 0) + " has backticks or line breaks"
+	case !utf8.ValidString(s):
+		return "identifier " + quote(s, 0) + " is not valid UTF-8"
+	}
+	return ""
+}
+
+// NormalizeIdentifier returns s as an identifier that can always be written,
+// such as the keys of external data. The backticks, line breaks and invalid
+// UTF-8 in s are replaced with _, and so is an empty s.
+func NormalizeIdentifier(s string) Identifier {
+	if s == "" {
+		return IdentifierBlank
+	}
+	s = strings.ToValidUTF8(s, "_")
+	return Identifier(strings.Map(func(r rune) rune {
+		if r == '`' || r == '\r' || r == '\n' {
+			return '_'
+		}
+		return r
+	}, s))
+}
+
+// quoteIdentifier returns s between backticks, unless it is a plain
+// identifier or empty.
+func quoteIdentifier(s string) string {
+	if s == "" || IsPlainIdentifier(s) {
+		return s
+	}
+	return "`" + s + "`"
 }
@@ -19,6 +19,36 @@ func TestIdentifierMarshal(t *testing.T) {
 			node: "foo",
 			res:  "foo",
 		},
+		{
+			name: "keyword",
+			node: "class",
+			res:  "`class`",
+		},
+		{
+			name: "invalid characters",
+			node: "default-timeout",
+			res:  "`default-timeout`",
+		},
+		{
+			name: "spaces",
+			node: "my key",
+			res:  "`my key`",
+		},
+		{
+			name: "leading digit",
+			node: "1st",
+			res:  "`1st`",
+		},
+		{
+			name: "blank",
+			node: IdentifierBlank,
+			res:  "_",
+		},
+		{
+			name: "unicode",
+			node: "$preço_1",
+			res:  "$preço_1",
+		},
 		{
 			name: "empty",
 			node: "",
@@ -48,6 +78,11 @@ func TestQualifiedIdentifierMarshal(t *testing.T) {
 			node: "foo.bar",
 			res:  "foo.bar",
 		},
+		{
+			name: "quoted parts",
+			node: "foo.import.bar-baz",
+			res:  "foo.`import`.`bar-baz`",
+		},
 		{
 			name: "empty",
 			node: "",
@@ -64,3 +99,150 @@ func TestQualifiedIdentifierMarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestIsPlainIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		res  bool
+	}{
+		{
+			name: "plain",
+			s:    "fooBar",
+			res:  true,
+		},
+		{
+			name: "dollar and underscore",
+			s:    "$_foo1",
+			res:  true,
+		},
+		{
+			name: "empty",
+			s:    "",
+			res:  false,
+		},
+		{
+			name: "keyword",
+			s:    "when",
+			res:  false,
+		},
+		{
+			name: "reserved keyword",
+			s:    "switch",
+			res:  false,
+		},
+		{
+			name: "leading digit",
+			s:    "1foo",
+			res:  false,
+		},
+		{
+			name: "dash",
+			s:    "foo-bar",
+			res:  false,
+		},
+		{
+			name: "dot",
+			s:    "foo.bar",
+			res:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, IsPlainIdentifier(test.s))
+		})
+	}
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		err  string
+	}{
+		{
+			name: "plain",
+			s:    "foo",
+		},
+		{
+			name: "quoted",
+			s:    "my key",
+		},
+		{
+			name: "empty",
+			s:    "",
+			err:  "ast: empty identifier",
+		},
+		{
+			name: "backtick",
+			s:    "a`b",
+			err:  "ast: identifier \"a`b\" has backticks or line breaks",
+		},
+		{
+			name: "line break",
+			s:    "a\nb",
+			err:  `ast: identifier "a\nb" has backticks or line breaks`,
+		},
+		{
+			name: "invalid utf-8",
+			s:    "a\xffb",
+			err:  "ast: identifier \"a\uFFFDb\" is not valid UTF-8",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateIdentifier(test.s)
+
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		res  Identifier
+	}{
+		{
+			name: "plain",
+			s:    "foo",
+			res:  "foo",
+		},
+		{
+			name: "quoted",
+			s:    "Content-Type",
+			res:  "Content-Type",
+		},
+		{
+			name: "empty",
+			s:    "",
+			res:  IdentifierBlank,
+		},
+		{
+			name: "backticks and line breaks",
+			s:    "a`b\r\nc",
+			res:  "a_b__c",
+		},
+		{
+			name: "invalid utf-8",
+			s:    "a\xff\xfeb",
+			res:  "a_b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := NormalizeIdentifier(test.s)
+
+			assert.Equal(t, test.res, res)
+			assert.NoError(t, ValidateIdentifier(string(res)))
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package ast
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pauloborges/balsamic/internal/bytesutil"
+)
+
+// When PrintConfig.MaxWidth is set, the nodes that can be wrapped print their
+// source code as a document, in the style of Wadler's "A prettier printer":
+// the places where lines can be broken are marked with the control bytes
+// below. The outermost node printing a document lays it out, breaking the
+// groups that do not fit in the line from the outermost to the innermost.
+const (
+	// Starts and ends a group, whose lines are either all broken or none.
+	docGroupStart = '\x01'
+	docGroupEnd   = '\x02'
+	// Starts and ends the indentation of the lines broken in a group.
+	docIndentStart = '\x03'
+	docIndentEnd   = '\x04'
+	// A space if the group is not broken, followed by the line break.
+	docLine = '\x05'
+	// Nothing if the group is not broken, followed by the line break.
+	docSoftLine = '\x06'
+	// Precedes a byte of text that is one of these control bytes.
+	docEscape = '\x07'
+)
+
+// isDocControl reports whether c is one of the control bytes of documents.
+func isDocControl(c rune) bool {
+	return c >= docGroupStart && c <= docEscape
+}
+
+// escapeDoc returns text written as is by a node, such as a comment, escaped
+// if it is part of a document, so that its control bytes are laid out as
+// text.
+func escapeDoc(ctx context.Context, text string) string {
+	if !getPrintState(ctx).inDoc || !strings.ContainsFunc(text, isDocControl) {
+		return text
+	}
+
+	var b strings.Builder
+	for _, c := range []byte(text) {
+		if isDocControl(rune(c)) {
+			b.WriteByte(docEscape)
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// docBuffer is a buffer for the source code of a node that can be wrapped. If
+// wrapping is disabled, it writes the source code as if no line was broken.
+type docBuffer struct {
+	bytesutil.Buffer
+	ctx  context.Context
+	wrap bool
+	// Whether the node is the outermost one printing a document.
+	root bool
+}
+
+// newDocBuffer returns the context to print the parts of a node that can be
+// wrapped, and the buffer for its source code.
+func newDocBuffer(ctx context.Context) (context.Context, *docBuffer) {
+	ctx, root := beginDoc(ctx)
+	return ctx, &docBuffer{ctx: ctx, wrap: getPrintConfig(ctx).MaxWidth > 0, root: root}
+}
+
+// Doc returns the source code written, laid out if the node is the
+// outermost one printing a document.
+func (b *docBuffer) Doc() []byte {
+	if b.root {
+		return layoutDoc(b.ctx, b.Bytes())
+	}
+	return b.Bytes()
+}
+
+func (b *docBuffer) writeDoc(c byte) {
+	if b.wrap {
+		b.WriteByte(c)
+	}
+}
+
+func (b *docBuffer) StartGroup() { b.writeDoc(docGroupStart) }
+func (b *docBuffer) EndGroup()   { b.writeDoc(docGroupEnd) }
+func (b *docBuffer) Indent()     { b.writeDoc(docIndentStart) }
+func (b *docBuffer) Dedent()     { b.writeDoc(docIndentEnd) }
+
+// Line writes a space, or a line break if the group is broken.
+func (b *docBuffer) Line() {
+	if !b.wrap {
+		b.WriteRune(' ')
+		return
+	}
+	b.WriteByte(docLine)
+	b.WriteString(newlineWithIndentation(b.ctx))
+}
+
+// SoftLine writes a line break if the group is broken.
+func (b *docBuffer) SoftLine() {
+	if !b.wrap {
+		return
+	}
+	b.WriteByte(docSoftLine)
+	b.WriteString(newlineWithIndentation(b.ctx))
+}
+
+// writeList writes a group of items separated by commas, between open and
+// close. If the group is broken, each item goes in its own line.
+func (b *docBuffer) writeList(open string, items [][]byte, close string) {
+	b.WriteString(open)
+	if len(items) > 0 {
+		b.StartGroup()
+		b.Indent()
+		b.SoftLine()
+		for i, item := range items {
+			if i > 0 {
+				b.WriteRune(',')
+				b.Line()
+			}
+			b.Write(item)
+		}
+		b.Dedent()
+		b.SoftLine()
+		b.EndGroup()
+	}
+	b.WriteString(close)
+}
+
+// writeArguments writes the arguments of a call, if any. If they do not fit
+// in the line, each argument goes in its own line.
+func (b *docBuffer) writeArguments(parent Node, field string, args Expressions) error {
+	if args == nil {
+		return nil
+	}
+
+	items, err := marshalNodes(b.ctx, parent, field, args)
+	if err != nil {
+		return err
+	}
+	b.writeList("(", items, ")")
+
+	return nil
+}
+
+// beginDoc returns the context to print the parts of a node that can be
+// wrapped, and whether the node is the outermost one printing a document, in
+// which case it must lay out its source code with layoutDoc. Members are laid
+// out on their own, as they always start a line.
+func beginDoc(ctx context.Context) (context.Context, bool) {
+	state := getPrintState(ctx)
+	if state.config.MaxWidth == 0 || state.inDoc {
+		return ctx, false
+	}
+	state.inDoc = true
+	return context.WithValue(ctx, printStateKey, state), true
+}
+
+// withoutWrapping returns the context to print a node whose lines must not be
+// broken, such as an expression interpolated in a string.
+func withoutWrapping(ctx context.Context) context.Context {
+	state := getPrintState(ctx)
+	if state.config.MaxWidth == 0 {
+		return ctx
+	}
+	state.config.MaxWidth = 0
+	return context.WithValue(ctx, printStateKey, state)
+}
+
+// marshalNodes marshals each of the nodes of a list field of parent.
+func marshalNodes[N Node](ctx context.Context, parent Node, field string, nodes []N) ([][]byte, error) {
+	var items [][]byte
+	for i, node := range nodes {
+		item, err := marshalElement(ctx, parent, field, i, node)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// docToken is a part of a document: either text with no line breaks, a line
+// break, or one of the control bytes other than docEscape.
+type docToken struct {
+	kind byte
+	// The text, or the indentation after a line break.
+	text string
+}
+
+const (
+	docText    = 0
+	docNewline = '\n'
+)
+
+func tokenizeDoc(src []byte) []docToken {
+	var toks []docToken
+	for len(src) > 0 {
+		switch c := src[0]; c {
+		case docGroupStart, docGroupEnd, docIndentStart, docIndentEnd:
+			toks = append(toks, docToken{kind: c})
+			src = src[1:]
+		case docLine, docSoftLine, docNewline:
+			// Line breaks are followed by the indentation of their line.
+			if c != docNewline {
+				src = src[1:]
+			}
+			src = bytes.TrimPrefix(src, []byte{'\n'})
+			n := 0
+			for n < len(src) && (src[n] == ' ' || src[n] == '\t') {
+				n++
+			}
+			toks = append(toks, docToken{kind: c, text: string(src[:n])})
+			src = src[n:]
+		case docEscape:
+			// The escaped byte is text.
+			n := min(2, len(src))
+			toks = append(toks, docToken{kind: docText, text: string(src[1:n])})
+			src = src[n:]
+		default:
+			n := bytes.IndexFunc(src, func(r rune) bool { return isDocControl(r) || r == '\n' })
+			if n < 0 {
+				n = len(src)
+			}
+			toks = append(toks, docToken{kind: docText, text: string(src[:n])})
+			src = src[n:]
+		}
+	}
+	return toks
+}
+
+// layoutDoc lays out the document printed by a node in the given context,
+// breaking the lines of the groups that do not fit in PrintConfig.MaxWidth.
+// The indentation of a broken group is added to the lines after its line
+// breaks, including the lines of nested nodes.
+func layoutDoc(ctx context.Context, src []byte) []byte {
+	config := getPrintConfig(ctx)
+	unit := indentUnit(ctx)
+	toks := tokenizeDoc(src)
+
+	var b bytes.Buffer
+	col := width(config, indentation(ctx))
+	// The indentation of the current line, written along with its first
+	// text, so that blank lines have none.
+	indent, raw, written := "", "", true
+	// Whether each of the open groups is flat, that is, not broken.
+	var flat []bool
+	// Whether each of the open indentations applies, and how many do.
+	var indents []bool
+	extra := 0
+
+	isFlat := func() bool { return len(flat) > 0 && flat[len(flat)-1] }
+
+	writeText := func(text string) {
+		if !written {
+			b.WriteString(indent)
+			col = width(config, indent)
+			written = true
+		}
+		b.WriteString(text)
+		col += width(config, text)
+	}
+
+	newline := func(lineIndent string) {
+		if !written {
+			// Keep the whitespace of lines with no text as printed.
+			b.WriteString(raw)
+		}
+		b.WriteByte('\n')
+		indent, raw, written = lineIndent+strings.Repeat(unit, extra), lineIndent, false
+	}
+
+	for i, tok := range toks {
+		switch tok.kind {
+		case docText:
+			writeText(tok.text)
+		case docNewline:
+			newline(tok.text)
+		case docLine, docSoftLine:
+			if !isFlat() {
+				newline(tok.text)
+				raw = ""
+			} else if tok.kind == docLine {
+				writeText(" ")
+			}
+		case docGroupStart:
+			if isFlat() {
+				flat = append(flat, true)
+				continue
+			}
+			start := col
+			if !written {
+				start = width(config, indent)
+			}
+			flat = append(flat, fits(config, toks[i+1:], int(config.MaxWidth)-start))
+		case docGroupEnd:
+			flat = flat[:len(flat)-1]
+		case docIndentStart:
+			indents = append(indents, !isFlat())
+			if !isFlat() {
+				extra++
+			}
+		case docIndentEnd:
+			if indents[len(indents)-1] {
+				extra--
+			}
+			indents = indents[:len(indents)-1]
+		}
+	}
+	if !written {
+		b.WriteString(raw)
+	}
+
+	return b.Bytes()
+}
+
+// fits reports whether the group starting at toks fits in the given width if
+// not broken, along with the text following it up to the next line break.
+func fits(config PrintConfig, toks []docToken, remaining int) bool {
+	depth := 1
+	for _, tok := range toks {
+		switch tok.kind {
+		case docText:
+			remaining -= width(config, tok.text)
+		case docLine:
+			if depth == 0 {
+				return true
+			}
+			remaining--
+		case docSoftLine:
+			if depth == 0 {
+				return true
+			}
+		case docNewline:
+			return remaining >= 0
+		case docGroupStart:
+			depth++
+		case docGroupEnd:
+			depth--
+		}
+		if remaining < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// width returns the number of columns of text. Tabs take
+// PrintConfig.IndentWidth columns.
+func width(config PrintConfig, text string) int {
+	tabs := strings.Count(text, "\t")
+	return utf8.RuneCountInString(text) - tabs + tabs*int(config.IndentWidth)
+}
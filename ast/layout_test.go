@@ -0,0 +1,286 @@
+package ast
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout(t *testing.T) {
+	access := func(name Identifier) Expression {
+		return &MemberAccessExpression{Name: name}
+	}
+
+	tests := []struct {
+		name        string
+		maxWidth    uint
+		indentLevel uint
+		node        Node
+		res         string
+	}{
+		{
+			name:     "arguments that fit",
+			maxWidth: 40,
+			node: &MemberAccessExpression{
+				Name:      "max",
+				Arguments: Expressions{access("first"), access("second")},
+			},
+			res: `max(first, second)`,
+		},
+		{
+			name:     "arguments that do not fit",
+			maxWidth: 15,
+			node: &MemberAccessExpression{
+				Name:      "max",
+				Arguments: Expressions{access("first"), access("second")},
+			},
+			res: stringsutil.StripMargin(`
+				|max(
+				|  first,
+				|  second
+				|)
+			`),
+		},
+		{
+			name:        "indented arguments",
+			maxWidth:    20,
+			indentLevel: 1,
+			node: &SuperAccessExpression{
+				Name:      "max",
+				Arguments: Expressions{access("first"), access("second")},
+			},
+			res: stringsutil.StripMargin(`
+				|super.max(
+				|    first,
+				|    second
+				|  )
+			`),
+		},
+		{
+			name:     "nested arguments",
+			maxWidth: 25,
+			node: &MemberAccessExpression{
+				Name: "max",
+				Arguments: Expressions{
+					&MemberAccessExpression{
+						Name:      "min",
+						Arguments: Expressions{access("first"), access("second")},
+					},
+					access("third"),
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|max(
+				|  min(first, second),
+				|  third
+				|)
+			`),
+		},
+		{
+			name:     "union type",
+			maxWidth: 20,
+			node: &UnionType{
+				Members: []Type{StringLiteralType("alpha"), StringLiteralType("bravo")},
+				Default: StringLiteralType("charlie"),
+			},
+			res: stringsutil.StripMargin(`
+				|"alpha"
+				|  | "bravo"
+				|  | *"charlie"
+			`),
+		},
+		{
+			name:     "type parameters",
+			maxWidth: 20,
+			node: &DeclaredType{
+				Name: "Mapping",
+				TypeParameters: []Type{
+					&DeclaredType{Name: "String"},
+					&DeclaredType{Name: "Listing", TypeParameters: []Type{&DeclaredType{Name: "Int"}}},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|Mapping<
+				|  String,
+				|  Listing<Int>
+				|>
+			`),
+		},
+		{
+			name:     "binary operator chain",
+			maxWidth: 20,
+			node: &BinaryExpression{
+				Operator: BinaryOperatorLogicalAnd,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorLogicalAnd,
+					Left:     access("isEnabled"),
+					Right:    access("isReady"),
+				},
+				Right: access("isValid"),
+			},
+			res: stringsutil.StripMargin(`
+				|isEnabled &&
+				|  isReady &&
+				|  isValid
+			`),
+		},
+		{
+			name:     "method call chain",
+			maxWidth: 20,
+			node: &QualifiedMemberAccessExpression{
+				Receiver: &QualifiedMemberAccessExpression{
+					Receiver:  access("items"),
+					Name:      "map",
+					Arguments: Expressions{access("f")},
+				},
+				Nullable:  true,
+				Name:      "filter",
+				Arguments: Expressions{access("g")},
+			},
+			res: stringsutil.StripMargin(`
+				|items
+				|  .map(f)
+				|  ?.filter(g)
+			`),
+		},
+		{
+			name:     "single method call",
+			maxWidth: 10,
+			node: &QualifiedMemberAccessExpression{
+				Receiver:  access("items"),
+				Name:      "map",
+				Arguments: Expressions{access("f")},
+			},
+			res: stringsutil.StripMargin(`
+				|items.map(
+				|  f
+				|)
+			`),
+		},
+		{
+			name:     "members",
+			maxWidth: 30,
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectProperty{
+						Name: "port",
+						Value: &MemberAccessExpression{
+							Name:      "max",
+							Arguments: Expressions{access("defaultPort"), access("port")},
+						},
+					},
+					&ObjectProperty{
+						Name: "server",
+						Value: &MemberAccessExpression{
+							Name: "connect",
+							Arguments: Expressions{
+								access("host"),
+								&NewExpression{
+									Body: &ObjectBody{
+										Members: ObjectMembers{
+											&ObjectProperty{Name: "timeout", Value: IntExpression(30)},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|{
+				|  port = max(
+				|    defaultPort,
+				|    port
+				|  )
+				|  server = connect(host, new {
+				|    timeout = 30
+				|  })
+				|}
+			`),
+		},
+		{
+			name:     "method parameters",
+			maxWidth: 42,
+			node: &Class{
+				Name: "Converter",
+				Members: []ClassMember{
+					&ClassMethod{
+						Signature: &MethodSignature{
+							Name: "convert",
+							Parameters: Parameters{
+								&Parameter{Name: "source", Type: &DeclaredType{Name: "String"}},
+								&Parameter{Name: "count", Type: &DeclaredType{Name: "Int"}},
+							},
+							Result: &DeclaredType{
+								Name: "Listing",
+								TypeParameters: []Type{
+									&DeclaredType{
+										Name:           "Mapping",
+										TypeParameters: []Type{&DeclaredType{Name: "String"}, &DeclaredType{Name: "Int"}},
+									},
+								},
+							},
+						},
+						Implementation: access("impl"),
+					},
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|class Converter {
+				|  function convert(
+				|    source: String,
+				|    count: Int
+				|  ): Listing<Mapping<String, Int>> = impl
+				|}
+			`),
+		},
+		{
+			name:     "control bytes in comments and identifiers",
+			maxWidth: 20,
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					LineComment("a\x05b\x01c"),
+					BlockComment("\x07"),
+					&ObjectProperty{
+						LeadingComments: Comments{LineComment("\x06")},
+						Name:            "p\x02q",
+						Value: &MemberAccessExpression{
+							Name:      "max",
+							Arguments: Expressions{access("first"), access("second")},
+						},
+						TrailingComment: "\x03\x04",
+					},
+				},
+			},
+			res: "{\n  // a\x05b\x01c\n\n  /*\n    \x07\n  */\n\n  // \x06\n  `p\x02q` = max(\n    first,\n    second\n  ) // \x03\x04\n}",
+		},
+		{
+			name: "no wrapping",
+			node: &MemberAccessExpression{
+				Name:      "max",
+				Arguments: Expressions{access("first"), access("second")},
+			},
+			res: `max(first, second)`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Given
+			config := DefaultPrintConfig
+			config.MaxWidth = test.maxWidth
+			ctx := ctxWithIndentLevel(context.Background(), test.indentLevel)
+			ctx = WithPrintConfig(ctx, config)
+
+			// When
+			result, err := test.node.Marshal(ctx)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(result))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package ast
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/pauloborges/balsamic/internal/bytesutil"
+)
+
+// ErrRequired is the error of marshaling a node with a required field that is
+// not set.
+var ErrRequired = errors.New("required field is not set")
+
+// MarshalError is an error marshaling a node, such as ErrRequired.
+type MarshalError struct {
+	// The kind of the node with the offending field, as in ObjectElement.
+	Node string
+	// The offending field of the node, as in Value.
+	Field string
+	// The path to the offending field from the marshaled node, as in
+	// Members[2].Body.Members[5].Value.
+	Path string
+	Err  error
+}
+
+func (e *MarshalError) Error() string {
+	return "ast: marshal " + e.Path + " (" + e.Node + "." + e.Field + "): " + e.Err.Error()
+}
+
+func (e *MarshalError) Unwrap() error {
+	return e.Err
+}
+
+// nodeKind returns the name of the type of a node, or a part of a node such as
+// a StringInterpolation, without the pointer.
+func nodeKind(n any) string {
+	t := reflect.TypeOf(n)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// requiredError returns the error of a required field of parent that is not
+// set.
+func requiredError(parent any, field string) error {
+	return &MarshalError{Node: nodeKind(parent), Field: field, Path: field, Err: ErrRequired}
+}
+
+// fieldError returns err, the error of marshaling the node of a field of
+// parent, with the field prepended to its path. The lists of nodes, such as
+// ObjectMembers, are transparent: the errors of their elements are reported
+// as errors of the field of parent holding the list.
+func fieldError(parent any, field string, err error) error {
+	marshalErr, ok := err.(*MarshalError)
+	if !ok {
+		return &MarshalError{Node: nodeKind(parent), Field: field, Path: field, Err: err}
+	}
+
+	if marshalErr.Path == marshalErr.Field && marshalErr.Path[0] == '[' {
+		marshalErr.Node, marshalErr.Field = nodeKind(parent), field+marshalErr.Field
+	}
+	return prefixPath(field, marshalErr)
+}
+
+// prefixPath returns err, the error of marshaling a node at path from the
+// node being marshaled, with path prepended to its path.
+func prefixPath(path string, err error) error {
+	marshalErr, ok := err.(*MarshalError)
+	if !ok || path == "" {
+		return err
+	}
+
+	if marshalErr.Path[0] == '[' {
+		marshalErr.Path = path + marshalErr.Path
+	} else {
+		marshalErr.Path = path + "." + marshalErr.Path
+	}
+	return marshalErr
+}
+
+// marshalField returns the source code of the node of a required field of
+// parent.
+func marshalField(ctx context.Context, parent Node, field string, n Node) ([]byte, error) {
+	if isNil(n) {
+		return nil, requiredError(parent, field)
+	}
+
+	p, err := n.Marshal(ctx)
+	if err != nil {
+		return nil, fieldError(parent, field, err)
+	}
+	return p, nil
+}
+
+// marshalElement returns the source code of the i-th node of a list field of
+// parent. The path to the node is only built on errors.
+func marshalElement(ctx context.Context, parent Node, field string, i int, n Node) ([]byte, error) {
+	if isNil(n) {
+		return nil, requiredError(parent, index(field, i))
+	}
+
+	p, err := n.Marshal(ctx)
+	if err != nil {
+		return nil, fieldError(parent, index(field, i), err)
+	}
+	return p, nil
+}
+
+// marshalOptionalField returns the source code of the node of an optional
+// field of parent, or nothing if it is not set.
+func marshalOptionalField(ctx context.Context, parent Node, field string, n Node) ([]byte, error) {
+	if isNil(n) {
+		return nil, nil
+	}
+	return marshalField(ctx, parent, field, n)
+}
+
+// MarshalTo pretty-prints a node to w, as Marshal does, and returns the
+// number of bytes written. The source code is written as it is printed, a
+// few members at a time, so part of it may have been written when an error
+// is returned.
+func MarshalTo(ctx context.Context, w io.Writer, n Node) (int64, error) {
+	b := bytesutil.NewWriterBuffer(w)
+	if err := writeNode(ctx, b, n); err != nil {
+		return b.Flushed(), err
+	}
+	b.Flush()
+	return b.Flushed(), b.Err()
+}
+
+// flushSize is the number of buffered bytes from which the source code
+// printed by MarshalTo is written.
+const flushSize = 16 << 10
+
+// flushMember flushes the source code written to b after a member, if enough
+// of it is buffered. The bytes of a member that is not empty are never
+// truncated, so neither are the bytes before them.
+func flushMember(b *bytesutil.Buffer) {
+	if b.Buffered() >= flushSize {
+		b.Flush()
+	}
+}
+
+// bufferWriter is implemented by the nodes that can write their source code
+// to a buffer shared with their parent.
+type bufferWriter interface {
+	writeTo(ctx context.Context, b *bytesutil.Buffer) error
+}
+
+// marshalNode returns the source code of a node that writes it to a buffer.
+func marshalNode(ctx context.Context, n bufferWriter) ([]byte, error) {
+	var b bytesutil.Buffer
+	if err := n.writeTo(ctx, &b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeNode writes the source code of a node to b.
+func writeNode(ctx context.Context, b *bytesutil.Buffer, n Node) error {
+	if n, ok := n.(bufferWriter); ok {
+		return n.writeTo(ctx, b)
+	}
+
+	p, err := n.Marshal(ctx)
+	if err != nil {
+		return err
+	}
+	b.Write(p)
+
+	return nil
+}
+
+// writeField writes the source code of the node of a required field of
+// parent to b.
+func writeField(ctx context.Context, b *bytesutil.Buffer, parent Node, field string, n Node) error {
+	if isNil(n) {
+		return requiredError(parent, field)
+	}
+
+	if err := writeNode(ctx, b, n); err != nil {
+		return fieldError(parent, field, err)
+	}
+	return nil
+}
+
+// writeElement writes the source code of the i-th node of a list field of
+// parent to b. The path to the node is only built on errors.
+func writeElement(ctx context.Context, b *bytesutil.Buffer, parent Node, field string, i int, n Node) error {
+	if isNil(n) {
+		return requiredError(parent, index(field, i))
+	}
+
+	if err := writeNode(ctx, b, n); err != nil {
+		return fieldError(parent, index(field, i), err)
+	}
+	return nil
+}
+
+// writeFieldWithPrefixSuffix writes the source code of the node of an
+// optional field of parent to b, preceded by prefix and followed by suffix.
+// Like bytesutil.Buffer.WriteWithPrefix, it writes nothing if the node is not
+// set or empty.
+func writeFieldWithPrefixSuffix(ctx context.Context, b *bytesutil.Buffer, prefix string, parent Node, field string, n Node, suffix string) error {
+	if isNil(n) {
+		return nil
+	}
+	return writeWithPrefixSuffix(b, prefix, func() error {
+		return writeField(ctx, b, parent, field, n)
+	}, suffix)
+}
+
+// writeWithPrefixSuffix writes to b what write writes, preceded by prefix and
+// followed by suffix, only if write writes something.
+func writeWithPrefixSuffix(b *bytesutil.Buffer, prefix string, write func() error, suffix string) error {
+	mark := b.Len()
+	b.WriteString(prefix)
+
+	start := b.Len()
+	if err := write(); err != nil {
+		return err
+	}
+	if b.Len() == start {
+		b.Truncate(mark)
+		return nil
+	}
+	b.WriteString(suffix)
+
+	return nil
+}
+
+// writeNodes writes the source code of the nodes of a list field of parent
+// to b, separated by separator. None of the nodes can be nil. The field of a
+// list that is a node itself, such as ObjectMembers, is empty.
+func writeNodes[N Node](ctx context.Context, b *bytesutil.Buffer, parent Node, field string, nodes []N, separator string) error {
+	for i, node := range nodes {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+		if err := writeElement(ctx, b, parent, field, i, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package ast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// largeModule returns a module with the given number of properties, each
+// amending objects nested to the given depth.
+func largeModule(properties, depth int) *Module {
+	m := &Module{Name: "Large"}
+	for i := range properties {
+		body := &ObjectBody{
+			Members: ObjectMembers{
+				&ObjectProperty{Name: "name", Value: StringExpression(fmt.Sprintf("property %d", i))},
+				&ObjectEntry{Key: StringExpression("count"), Value: IntExpression(i)},
+			},
+		}
+		for j := range depth {
+			body = &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectProperty{
+						LeadingComments: Comments{LineComment(fmt.Sprintf("Level %d.", j))},
+						Name:            Identifier(fmt.Sprintf("level%d", j)),
+						Body:            []*ObjectBody{body},
+					},
+					&ObjectElement{
+						Value: &BinaryExpression{
+							Operator: BinaryOperatorPlus,
+							Left:     IntExpression(j),
+							Right:    &MemberAccessExpression{Name: "offset"},
+						},
+					},
+				},
+			}
+		}
+		m.Members = append(m.Members, &ClassProperty{
+			Name:       Identifier(fmt.Sprintf("property%d", i)),
+			Expression: &NewExpression{Body: body},
+		})
+	}
+	return m
+}
+
+func TestMarshalTo(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		node Node
+	}{
+		{
+			name: "module",
+			node: largeModule(3, 3),
+		},
+		{
+			name: "object body",
+			node: largeModule(1, 2).Members[0].(*ClassProperty).Expression,
+		},
+		{
+			name: "expression",
+			node: IntExpression(42),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Given
+			expected, err := test.node.Marshal(ctx)
+			assert.NoError(t, err)
+
+			// When
+			var b bytes.Buffer
+			n, err := MarshalTo(ctx, &b, test.node)
+
+			// Then
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(expected)), n)
+			assert.Equal(t, string(expected), b.String())
+		})
+	}
+}
+
+// countingWriter counts the writes to it, and fails after failAfter writes if
+// set.
+type countingWriter struct {
+	bytes.Buffer
+	writes    int
+	failAfter int
+}
+
+var errWrite = errors.New("write failed")
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.failAfter > 0 && w.writes == w.failAfter {
+		return 0, errWrite
+	}
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestMarshalToStreams(t *testing.T) {
+	ctx := context.Background()
+	m := largeModule(200, 3)
+
+	expected, err := m.Marshal(ctx)
+	assert.NoError(t, err)
+
+	var w countingWriter
+	n, err := MarshalTo(ctx, &w, m)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(expected)), n)
+	assert.Equal(t, string(expected), w.String())
+	// The source code is written as it is printed, in chunks of about
+	// flushSize bytes.
+	assert.Greater(t, w.writes, len(expected)/(2*flushSize))
+}
+
+func TestMarshalToWriteError(t *testing.T) {
+	w := countingWriter{failAfter: 1}
+	n, err := MarshalTo(context.Background(), &w, largeModule(200, 3))
+
+	assert.Equal(t, errWrite, err)
+	assert.Equal(t, int64(w.Len()), n)
+	assert.Equal(t, 1, w.writes)
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		err  *MarshalError
+	}{
+		{
+			name: "if condition",
+			node: &IfExpression{Then: IntExpression(1), Else: IntExpression(2)},
+			err:  &MarshalError{Node: "IfExpression", Field: "Condition", Path: "Condition", Err: ErrRequired},
+		},
+		{
+			name: "let value",
+			node: &LetExpression{Name: &Parameter{Name: Identifier("a")}, Expression: IntExpression(1)},
+			err:  &MarshalError{Node: "LetExpression", Field: "Value", Path: "Value", Err: ErrRequired},
+		},
+		{
+			name: "for generator",
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ForGenerator{
+						Value:      &Parameter{Name: Identifier("x")},
+						Collection: &MemberAccessExpression{Name: "xs"},
+						Body: &ObjectBody{
+							Members: ObjectMembers{&ObjectElement{}},
+						},
+					},
+				},
+			},
+			err: &MarshalError{Node: "ObjectElement", Field: "Value", Path: "Members[0].Body.Members[0].Value", Err: ErrRequired},
+		},
+		{
+			name: "object entry key",
+			node: &ObjectBody{
+				Members: ObjectMembers{&ObjectEntry{Value: IntExpression(1)}},
+			},
+			err: &MarshalError{Node: "ObjectEntry", Field: "Key", Path: "Members[0].Key", Err: ErrRequired},
+		},
+		{
+			name: "nested path",
+			node: &Module{
+				Name: "foo",
+				Members: ModuleMembers{
+					&ClassProperty{Name: Identifier("a"), Expression: IntExpression(1)},
+					&ClassProperty{Name: Identifier("b"), Expression: IntExpression(2)},
+					&ClassProperty{
+						Name: Identifier("c"),
+						Body: &ObjectBody{
+							Members: ObjectMembers{
+								&ObjectElement{Value: IntExpression(0)},
+								&ObjectElement{Value: IntExpression(1)},
+								&ObjectElement{Value: IntExpression(2)},
+								&ObjectElement{Value: IntExpression(3)},
+								&ObjectElement{Value: IntExpression(4)},
+								&ObjectElement{},
+							},
+						},
+					},
+				},
+			},
+			err: &MarshalError{Node: "ObjectElement", Field: "Value", Path: "Members[2].Body.Members[5].Value", Err: ErrRequired},
+		},
+		{
+			name: "nil member",
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectElement{Value: IntExpression(0)},
+					nil,
+				},
+			},
+			err: &MarshalError{Node: "ObjectBody", Field: "Members[1]", Path: "Members[1]", Err: ErrRequired},
+		},
+		{
+			name: "nil pointer",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPlus,
+				Left:     (*MemberAccessExpression)(nil),
+				Right:    IntExpression(1),
+			},
+			err: &MarshalError{Node: "BinaryExpression", Field: "Left", Path: "Left", Err: ErrRequired},
+		},
+		{
+			name: "operation chain",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPlus,
+				Left: &BinaryExpression{
+					Operator: BinaryOperatorPlus,
+					Left:     IntExpression(1),
+					Right:    &MemberAccessExpression{Name: "f", Arguments: Expressions{nil}},
+				},
+				Right: IntExpression(3),
+			},
+			err: &MarshalError{Node: "MemberAccessExpression", Field: "Arguments[0]", Path: "Left.Right.Arguments[0]", Err: ErrRequired},
+		},
+		{
+			name: "interpolation",
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{StringText("a"), &StringInterpolation{}},
+			},
+			err: &MarshalError{Node: "StringInterpolation", Field: "Expression", Path: "Parts[1].Expression", Err: ErrRequired},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := test.node.Marshal(ctx)
+			assert.Equal(t, test.err, err)
+
+			_, err = MarshalTo(ctx, io.Discard, test.node)
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func TestMarshalErrorMessage(t *testing.T) {
+	_, err := (&IfExpression{Then: IntExpression(1), Else: IntExpression(2)}).Marshal(context.Background())
+
+	assert.EqualError(t, err, "ast: marshal Condition (IfExpression.Condition): required field is not set")
+	assert.True(t, errors.Is(err, ErrRequired))
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	ctx := context.Background()
+	m := largeModule(1000, 10)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := m.Marshal(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalTo(b *testing.B) {
+	ctx := context.Background()
+	m := largeModule(1000, 10)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := MarshalTo(ctx, io.Discard, m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package ast
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 type Modifier string
 
@@ -14,6 +17,17 @@ const (
 	ModifierOpen     Modifier = "open"
 )
 
+// modifierOrder is Pkl's canonical order of modifiers.
+var modifierOrder = []Modifier{
+	ModifierAbstract,
+	ModifierOpen,
+	ModifierExternal,
+	ModifierLocal,
+	ModifierHidden,
+	ModifierFixed,
+	ModifierConst,
+}
+
 func (m Modifier) Marshal(_ context.Context) ([]byte, error) {
 	return []byte(m), nil
 }
@@ -21,5 +35,80 @@ func (m Modifier) Marshal(_ context.Context) ([]byte, error) {
 type Modifiers []Modifier
 
 func (m Modifiers) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, m, " ")
+	if getPrintConfig(ctx).SortModifiers {
+		m = m.Sorted()
+	}
+	return joinNodes(ctx, m, "", m, " ")
+}
+
+// Sorted returns a copy of the modifiers in Pkl's canonical order: abstract,
+// open, external, local, hidden, fixed and const. Unknown modifiers go last,
+// in their original order.
+func (m Modifiers) Sorted() Modifiers {
+	sorted := slices.Clone(m)
+	slices.SortStableFunc(sorted, func(a, b Modifier) int {
+		return modifierRank(a) - modifierRank(b)
+	})
+	return sorted
+}
+
+func modifierRank(m Modifier) int {
+	if i := slices.Index(modifierOrder, m); i >= 0 {
+		return i
+	}
+	return len(modifierOrder)
+}
+
+// Validate checks that the modifiers are legal on a declaration of kind d:
+// that each of them is allowed on it, none is duplicated, and none conflicts
+// with another. It returns ValidationErrors with the index of each offending
+// modifier as path, or nil if there are none.
+func (m Modifiers) Validate(d Declaration) error {
+	var v validator
+	v.modifiers("", m, d)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// Declaration is a kind of declaration that can have modifiers.
+type Declaration string
+
+const (
+	DeclarationModule         Declaration = "module"
+	DeclarationAmendingModule Declaration = "amending module"
+	DeclarationClass          Declaration = "class"
+	DeclarationClassProperty  Declaration = "class property"
+	DeclarationMethod         Declaration = "method"
+	DeclarationTypeAlias      Declaration = "type alias"
+	DeclarationObjectMember   Declaration = "object member"
+)
+
+// declarationModifiers are the modifiers allowed on each kind of declaration.
+var declarationModifiers = map[Declaration][]Modifier{
+	DeclarationModule: {ModifierAbstract, ModifierOpen},
+	// An amending module does not declare a class of its own that could be
+	// abstract or open, so it takes no modifiers.
+	DeclarationAmendingModule: nil,
+	DeclarationClass:          {ModifierAbstract, ModifierOpen, ModifierExternal, ModifierLocal},
+	DeclarationClassProperty: {
+		ModifierAbstract,
+		ModifierExternal,
+		ModifierLocal,
+		ModifierHidden,
+		ModifierFixed,
+		ModifierConst,
+	},
+	DeclarationMethod:       {ModifierAbstract, ModifierExternal, ModifierLocal, ModifierConst},
+	DeclarationTypeAlias:    {ModifierLocal},
+	DeclarationObjectMember: {ModifierLocal},
+}
+
+// conflictingModifiers are the pairs of modifiers that can not be set
+// together. Local members can not be overridden nor rendered, so they can not
+// be abstract nor hidden.
+var conflictingModifiers = [][2]Modifier{
+	{ModifierLocal, ModifierAbstract},
+	{ModifierLocal, ModifierHidden},
 }
@@ -60,3 +60,110 @@ func TestModifierMarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestModifiersMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		node Modifiers
+		sort bool
+		res  string
+	}{
+		{
+			name: "original order",
+			node: Modifiers{ModifierHidden, ModifierLocal, ModifierAbstract},
+			res:  "hidden local abstract",
+		},
+		{
+			name: "canonical order",
+			node: Modifiers{ModifierConst, ModifierHidden, ModifierLocal, ModifierFixed, ModifierOpen, ModifierExternal, ModifierAbstract},
+			sort: true,
+			res:  "abstract open external local hidden fixed const",
+		},
+		{
+			name: "unknown modifier",
+			node: Modifiers{"foo", ModifierConst, ModifierLocal},
+			sort: true,
+			res:  "local const foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultPrintConfig
+			config.SortModifiers = test.sort
+			ctx := WithPrintConfig(context.Background(), config)
+
+			result, err := test.node.Marshal(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(result))
+		})
+	}
+}
+
+func TestModifiersSorted(t *testing.T) {
+	m := Modifiers{ModifierFixed, ModifierHidden}
+
+	assert.Equal(t, Modifiers{ModifierHidden, ModifierFixed}, m.Sorted())
+	assert.Equal(t, Modifiers{ModifierFixed, ModifierHidden}, m)
+}
+
+func TestModifiersValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		node        Modifiers
+		declaration Declaration
+		err         error
+	}{
+		{
+			name:        "none",
+			declaration: DeclarationTypeAlias,
+		},
+		{
+			name:        "legal",
+			node:        Modifiers{ModifierHidden, ModifierFixed, ModifierConst},
+			declaration: DeclarationClassProperty,
+		},
+		{
+			name:        "open object property",
+			node:        Modifiers{ModifierOpen},
+			declaration: DeclarationObjectMember,
+			err:         ValidationErrors{{Path: "[0]", Msg: `"open" is not a valid object member modifier`}},
+		},
+		{
+			name:        "external type alias",
+			node:        Modifiers{ModifierLocal, ModifierExternal},
+			declaration: DeclarationTypeAlias,
+			err:         ValidationErrors{{Path: "[1]", Msg: `"external" is not a valid type alias modifier`}},
+		},
+		{
+			name:        "conflicting",
+			node:        Modifiers{ModifierLocal, ModifierHidden, ModifierAbstract},
+			declaration: DeclarationClassProperty,
+			err: ValidationErrors{
+				{Path: "[1]", Msg: `can not be set together with "local"`},
+				{Path: "[2]", Msg: `can not be set together with "local"`},
+			},
+		},
+		{
+			name:        "duplicated",
+			node:        Modifiers{ModifierAbstract, ModifierOpen, ModifierAbstract},
+			declaration: DeclarationClass,
+			err:         ValidationErrors{{Path: "[2]", Msg: `duplicated modifier "abstract"`}},
+		},
+		{
+			name:        "invalid",
+			node:        Modifiers{"public"},
+			declaration: DeclarationMethod,
+			err:         ValidationErrors{{Path: "[0]", Msg: `invalid value "public"`}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.node.Validate(test.declaration)
+
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
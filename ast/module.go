@@ -2,7 +2,6 @@ package ast
 
 import (
 	"context"
-	"strconv"
 
 	"github.com/pauloborges/balsamic/internal/bytesutil"
 )
@@ -17,6 +16,12 @@ const (
 type Module struct {
 	// Optional.
 	ShebangComment ShebangComment
+	// Optional. Printed before the module header and separated from it by a
+	// blank line, such as a license.
+	LeadingComments Comments
+	// Optional. Printed right above the module header, with no blank line in
+	// between.
+	HeaderComments Comments
 	// Optional.
 	Docs Docs
 	// Optional.
@@ -34,41 +39,38 @@ type Module struct {
 	Imports ImportClauses
 	// Optional.
 	Members ModuleMembers
+
+	Span
 }
 
 func (m *Module) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	return marshalNode(ctx, m)
+}
 
-	shebang, err := m.ShebangComment.Marshal(ctx)
-	if err != nil {
-		return nil, err
+func (m *Module) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "ShebangComment", m.ShebangComment, "\n"); err != nil {
+		return err
 	}
-	b.WriteWithSuffix(shebang, "\n")
-
-	docs, err := m.Docs.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "LeadingComments", m.LeadingComments, "\n\n"); err != nil {
+		return err
 	}
-	b.WriteWithSuffix(docs, "\n")
-
-	annotations, err := m.Annotations.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "HeaderComments", m.HeaderComments, "\n"); err != nil {
+		return err
 	}
-	b.WriteWithSuffix(annotations, "\n")
-
-	modifiers, err := m.Modifiers.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "Docs", m.Docs, "\n"); err != nil {
+		return err
+	}
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "Annotations", m.Annotations, "\n"); err != nil {
+		return err
+	}
+	if err := writeFieldWithPrefixSuffix(ctx, b, "", m, "Modifiers", m.Modifiers, " "); err != nil {
+		return err
 	}
-	b.WriteWithSuffix(modifiers, " ")
 
 	if m.Name != "" {
-		name, err := m.Name.Marshal(ctx)
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, "module ", m, "Name", m.Name, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix("module ", name)
 	}
 
 	if m.ParentName != "" {
@@ -77,24 +79,15 @@ func (m *Module) Marshal(ctx context.Context) ([]byte, error) {
 		}
 		b.WriteString(string(m.ParentRelationship))
 		b.WriteRune(' ')
-		b.WriteString(strconv.Quote(m.ParentName))
+		b.WriteString(quote(m.ParentName, 0))
 	}
 
 	b.WriteRune('\n')
 
-	imports, err := m.Imports.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefixSuffix("\n", imports, "\n")
-
-	members, err := m.Members.Marshal(ctx)
-	if err != nil {
-		return nil, err
+	if err := writeFieldWithPrefixSuffix(ctx, b, "\n", m, "Imports", m.Imports, "\n"); err != nil {
+		return err
 	}
-	b.WriteWithPrefixSuffix("\n", members, "\n")
-
-	return b.Bytes(), nil
+	return writeFieldWithPrefixSuffix(ctx, b, "\n", m, "Members", m.Members, "\n")
 }
 
 type ImportClause struct {
@@ -104,6 +97,12 @@ type ImportClause struct {
 	Alias string
 	// Optional.
 	Glob bool
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (i *ImportClause) Marshal(ctx context.Context) ([]byte, error) {
@@ -115,20 +114,20 @@ func (i *ImportClause) Marshal(ctx context.Context) ([]byte, error) {
 		b.WriteString("import ")
 	}
 
-	b.WriteString(strconv.Quote(i.Path))
+	b.WriteString(quote(i.Path, 0))
 
 	if i.Alias != "" {
 		b.WriteString(" as ")
-		b.WriteString(i.Alias)
+		b.WriteString(quoteIdentifier(i.Alias))
 	}
 
-	return b.Bytes(), nil
+	return withComments(ctx, i, i.LeadingComments, i.TrailingComment, b.Bytes())
 }
 
 type ImportClauses []*ImportClause
 
 func (i ImportClauses) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, i, "\n")
+	return joinNodes(ctx, i, "", i, "\n")
 }
 
 type ModuleMember interface {
@@ -139,5 +138,29 @@ type ModuleMember interface {
 type ModuleMembers []ModuleMember
 
 func (m ModuleMembers) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, m, "\n\n")
+	return marshalNode(ctx, m)
+}
+
+func (m ModuleMembers) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeMembers(ctx, b, m, "", m, true)
+}
+
+// ModuleMemberGroup is a group of related module members. Unlike other
+// module members, the members of a group are not separated by blank lines.
+type ModuleMemberGroup struct {
+	// Required.
+	Members ModuleMembers
+
+	Span
+}
+
+func (g *ModuleMemberGroup) isModuleMember() {}
+func (g *ModuleMemberGroup) isMemberGroup()  {}
+
+func (g *ModuleMemberGroup) Marshal(ctx context.Context) ([]byte, error) {
+	return marshalNode(ctx, g)
+}
+
+func (g *ModuleMemberGroup) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeMembers(ctx, b, g, "Members", g.Members, false)
 }
@@ -30,6 +30,14 @@ func TestImportClauseTest(t *testing.T) {
 			},
 			res: `import "@foo/Bar.pkl" as Baz`,
 		},
+		{
+			name: "with keyword alias",
+			node: ImportClause{
+				Path:  "@foo/Bar.pkl",
+				Alias: "module",
+			},
+			res: "import \"@foo/Bar.pkl\" as `module`",
+		},
 		{
 			name: "with glob",
 			node: ImportClause{
@@ -159,6 +167,45 @@ func TestModuleMembersMarshal(t *testing.T) {
 				|class Foo
 			`),
 		},
+		{
+			name: "groups",
+			node: ModuleMembers{
+				&ModuleMemberGroup{
+					Members: ModuleMembers{
+						&ClassProperty{Name: Identifier("host"), Expression: StringExpression("localhost")},
+						&ClassProperty{Name: Identifier("port"), Expression: IntExpression(8080)},
+					},
+				},
+				&ClassProperty{Name: Identifier("debug"), Expression: ExpressionTrue},
+			},
+			res: stringsutil.StripMargin(`
+				|host = "localhost"
+				|port = 8080
+				|
+				|debug = true
+			`),
+		},
+		{
+			name: "comments",
+			node: ModuleMembers{
+				LineComment("Standalone."),
+				&ClassProperty{
+					LeadingComments: Comments{LineComment("TODO: remove."), BlockComment("Deprecated.")},
+					Name:            Identifier("foo"),
+					Expression:      IntExpression(42),
+					TrailingComment: "Seconds.",
+				},
+			},
+			res: stringsutil.StripMargin(`
+				|// Standalone.
+				|
+				|// TODO: remove.
+				|/*
+				|  Deprecated.
+				|*/
+				|foo = 42 // Seconds.
+			`),
+		},
 	}
 
 	for _, test := range tests {
@@ -222,6 +269,41 @@ func TestModuleMarshal(t *testing.T) {
 				|
 			`),
 		},
+		{
+			name: "leading comments",
+			node: Module{
+				Name:            "foo.bar",
+				ShebangComment:  ShebangComment("/usr/bin/pkl eval"),
+				LeadingComments: Comments{LineComment("Copyright 2024.\nAll rights reserved.")},
+				Docs:            Docs("This is a test module"),
+			},
+			res: stringsutil.StripMargin(`
+				|#! /usr/bin/pkl eval
+				|// Copyright 2024.
+				|// All rights reserved.
+				|
+				|/// This is a test module
+				|module foo.bar
+				|
+			`),
+		},
+		{
+			name: "header comments",
+			node: Module{
+				Name:            "foo.bar",
+				LeadingComments: Comments{LineComment("Copyright 2024.")},
+				HeaderComments:  Comments{LineComment("Header.")},
+				Docs:            Docs("This is a test module"),
+			},
+			res: stringsutil.StripMargin(`
+				|// Copyright 2024.
+				|
+				|// Header.
+				|/// This is a test module
+				|module foo.bar
+				|
+			`),
+		},
 		{
 			name: "docs",
 			node: Module{
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"strings"
+
+	"github.com/pauloborges/balsamic/internal/bytesutil"
 )
 
 // Node represents a node in Pkl's abstract syntax tree.
@@ -14,37 +16,36 @@ type Node interface {
 
 type ctxKey string
 
-var indentLevelKey ctxKey = "indentLevel"
-
 func ctxWithIndentLevel(ctx context.Context, indentLevel uint) context.Context {
-	return context.WithValue(ctx, indentLevelKey, indentLevel)
+	state := getPrintState(ctx)
+	state.indentLevel = indentLevel
+	state.newline = state.config.newline(indentLevel)
+	return context.WithValue(ctx, printStateKey, state)
 }
 
 func raiseIndentLevel(ctx context.Context) context.Context {
-	indentLevel := getIndentLevel(ctx)
-	return context.WithValue(ctx, indentLevelKey, indentLevel+1)
+	return ctxWithIndentLevel(ctx, getIndentLevel(ctx)+1)
 }
 
 func getIndentLevel(ctx context.Context) uint {
-	if indent, ok := ctx.Value(indentLevelKey).(uint); ok {
-		return indent
-	}
-	return 0
+	return getPrintState(ctx).indentLevel
+}
+
+func indentUnit(ctx context.Context) string {
+	return getPrintConfig(ctx).indentUnit()
 }
 
 func indentation(ctx context.Context) string {
-	indentLevel := getIndentLevel(ctx)
-	if indentLevel == 0 {
-		return ""
-	}
-	return strings.Repeat("  ", int(indentLevel))
+	return newlineWithIndentation(ctx)[1:]
 }
 
 func newlineWithIndentation(ctx context.Context) string {
-	return "\n" + indentation(ctx)
+	return getPrintState(ctx).newline
 }
 
-func joinNodes[N Node](ctx context.Context, nodes []N, separator string) ([]byte, error) {
+// joinNodes returns the source code of the nodes of a list field of parent,
+// separated by separator. None of the nodes can be nil.
+func joinNodes[N Node](ctx context.Context, parent Node, field string, nodes []N, separator string) ([]byte, error) {
 	var b bytes.Buffer
 
 	if len(nodes) == 0 {
@@ -56,7 +57,7 @@ func joinNodes[N Node](ctx context.Context, nodes []N, separator string) ([]byte
 			b.WriteString(separator)
 		}
 
-		node, err := node.Marshal(ctx)
+		node, err := marshalElement(ctx, parent, field, i, node)
 		if err != nil {
 			return nil, err
 		}
@@ -66,3 +67,56 @@ func joinNodes[N Node](ctx context.Context, nodes []N, separator string) ([]byte
 
 	return b.Bytes(), nil
 }
+
+// memberGroup is implemented by the nodes that group related members.
+type memberGroup interface {
+	isMemberGroup()
+}
+
+// writeMembers writes the members of a list field of parent to b in their own
+// lines, with blank lines between them if blank is set. Member groups are
+// always separated from the other members by blank lines. So are standalone
+// comments outside member groups, by at least one, or they would lead the
+// next member when parsed.
+func writeMembers[N Node](ctx context.Context, b *bytesutil.Buffer, parent Node, field string, nodes []N, blank bool) error {
+	ctx, root := beginDoc(ctx)
+	newline := newlineWithIndentation(ctx)
+	blankLines := strings.Repeat("\n", int(getPrintConfig(ctx).BlankLines))
+	_, inGroup := parent.(memberGroup)
+
+	for i, node := range nodes {
+		if i > 0 {
+			_, group := any(node).(memberGroup)
+			_, prevGroup := any(nodes[i-1]).(memberGroup)
+			_, comment := any(node).(Comment)
+			_, prevComment := any(nodes[i-1]).(Comment)
+			comment = (comment || prevComment) && !inGroup
+			switch {
+			case comment && blankLines == "":
+				b.WriteString("\n")
+			case blank || group || prevGroup || comment:
+				b.WriteString(blankLines)
+			}
+			b.WriteString(newline)
+		}
+
+		start := b.Len()
+		if !root {
+			if err := writeElement(ctx, b, parent, field, i, node); err != nil {
+				return err
+			}
+		} else {
+			// Members are laid out on their own, as they always start a line.
+			p, err := marshalElement(ctx, parent, field, i, node)
+			if err != nil {
+				return err
+			}
+			b.Write(layoutDoc(ctx, p))
+		}
+		if b.Len() > start {
+			flushMember(b)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IntRadix is the radix in which an integer literal is written.
+type IntRadix int
+
+const (
+	IntRadixDecimal     IntRadix = 10
+	IntRadixHexadecimal IntRadix = 16
+	IntRadixBinary      IntRadix = 2
+	IntRadixOctal       IntRadix = 8
+)
+
+var radixPrefixes = map[IntRadix]string{
+	IntRadixHexadecimal: "0x",
+	IntRadixBinary:      "0b",
+	IntRadixOctal:       "0o",
+}
+
+// formatInt returns v as a Pkl integer literal in the given radix, with at
+// least the given number of digits, and the digits grouped by underscores in
+// groups of the given size, counting from the right. A negative v is written
+// with a minus sign before the radix prefix, as in -0xFF.
+func formatInt(v int64, radix IntRadix, digits, groupSize uint) string {
+	if _, ok := radixPrefixes[radix]; !ok {
+		radix = IntRadixDecimal
+	}
+
+	u := uint64(v)
+	if v < 0 {
+		u = -u
+	}
+	s := strings.ToUpper(strconv.FormatUint(u, int(radix)))
+	if n := int(digits) - len(s); n > 0 {
+		s = strings.Repeat("0", n) + s
+	}
+
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(radixPrefixes[radix])
+	for i := range len(s) {
+		if i > 0 && groupSize > 0 && (len(s)-i)%int(groupSize) == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// formatFloat returns v as a Pkl float literal, which is never read as an
+// integer: it has a decimal point or an exponent, or is NaN or Infinity.
+// Exponents are used for the values that would otherwise need more than 21
+// digits, as in 1e+21 or 1e-07.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+
+	format := byte('f')
+	if abs := math.Abs(v); abs >= 1e21 || (abs != 0 && abs < 1e-6) {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(v, format, -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
@@ -0,0 +1,153 @@
+package ast
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatInt(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         int64
+		radix     IntRadix
+		digits    uint
+		groupSize uint
+		res       string
+	}{
+		{
+			name: "default radix",
+			v:    42,
+			res:  "42",
+		},
+		{
+			name:  "hexadecimal",
+			v:     0xCAFE,
+			radix: IntRadixHexadecimal,
+			res:   "0xCAFE",
+		},
+		{
+			name:  "binary",
+			v:     5,
+			radix: IntRadixBinary,
+			res:   "0b101",
+		},
+		{
+			name:  "octal",
+			v:     0o644,
+			radix: IntRadixOctal,
+			res:   "0o644",
+		},
+		{
+			name:  "unknown radix",
+			v:     42,
+			radix: 7,
+			res:   "42",
+		},
+		{
+			name:   "padded",
+			v:      0x1F,
+			radix:  IntRadixHexadecimal,
+			digits: 8,
+			res:    "0x0000001F",
+		},
+		{
+			name:      "grouped",
+			v:         1000000,
+			groupSize: 3,
+			res:       "1_000_000",
+		},
+		{
+			name:      "padded and grouped",
+			v:         0xFF,
+			radix:     IntRadixBinary,
+			digits:    16,
+			groupSize: 4,
+			res:       "0b0000_0000_1111_1111",
+		},
+		{
+			name:  "negative",
+			v:     -255,
+			radix: IntRadixHexadecimal,
+			res:   "-0xFF",
+		},
+		{
+			name:  "minimum",
+			v:     math.MinInt64,
+			radix: IntRadixHexadecimal,
+			res:   "-0x8000000000000000",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, formatInt(test.v, test.radix, test.digits, test.groupSize))
+		})
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float64
+		res  string
+	}{
+		{
+			name: "zero",
+			v:    0,
+			res:  "0.0",
+		},
+		{
+			name: "negative zero",
+			v:    math.Copysign(0, -1),
+			res:  "-0.0",
+		},
+		{
+			name: "integral",
+			v:    100,
+			res:  "100.0",
+		},
+		{
+			name: "fraction",
+			v:    0.1,
+			res:  "0.1",
+		},
+		{
+			name: "large",
+			v:    1e21,
+			res:  "1e+21",
+		},
+		{
+			name: "largest without exponent",
+			v:    1e20,
+			res:  "100000000000000000000.0",
+		},
+		{
+			name: "small",
+			v:    1.5e-7,
+			res:  "1.5e-07",
+		},
+		{
+			name: "not a number",
+			v:    math.NaN(),
+			res:  "NaN",
+		},
+		{
+			name: "infinity",
+			v:    math.Inf(1),
+			res:  "Infinity",
+		},
+		{
+			name: "negative infinity",
+			v:    math.Inf(-1),
+			res:  "-Infinity",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, formatFloat(test.v))
+		})
+	}
+}
@@ -9,28 +9,32 @@ import (
 type ObjectBody struct {
 	Parameters Parameters
 	Members    ObjectMembers
+
+	Span
 }
 
 func (o *ObjectBody) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	return marshalNode(ctx, o)
+}
 
+func (o *ObjectBody) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
 	b.WriteRune('{')
 
-	params, err := joinNodes(ctx, o.Parameters, ", ")
+	err := writeWithPrefixSuffix(b, " ", func() error {
+		return writeNodes(ctx, b, o, "Parameters", o.Parameters, ", ")
+	}, " ->")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	b.WriteWithPrefixSuffix(" ", params, " ->")
 
-	members, err := o.Members.Marshal(ctx)
+	err = writeFieldWithPrefixSuffix(ctx, b, "", o, "Members", o.Members, newlineWithIndentation(ctx))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	b.WriteWithSuffix(members, newlineWithIndentation(ctx))
 
 	b.WriteRune('}')
 
-	return b.Bytes(), nil
+	return nil
 }
 
 type ObjectMember interface {
@@ -41,17 +45,34 @@ type ObjectMember interface {
 type ObjectMembers []ObjectMember
 
 func (m ObjectMembers) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	return marshalNode(ctx, m)
+}
 
+func (m ObjectMembers) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
 	raisedCtx := raiseIndentLevel(ctx)
-	raisedNl := newlineWithIndentation(raisedCtx)
-	members, err := joinNodes(raisedCtx, m, raisedNl)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefix(raisedNl, members)
+	return writeWithPrefixSuffix(b, newlineWithIndentation(raisedCtx), func() error {
+		return writeMembers(raisedCtx, b, m, "", m, false)
+	}, "")
+}
+
+// ObjectMemberGroup is a group of related object members. The group is
+// separated from the other members by blank lines.
+type ObjectMemberGroup struct {
+	// Required.
+	Members ObjectMembers
+
+	Span
+}
+
+func (g *ObjectMemberGroup) isObjectMember() {}
+func (g *ObjectMemberGroup) isMemberGroup()  {}
+
+func (g *ObjectMemberGroup) Marshal(ctx context.Context) ([]byte, error) {
+	return marshalNode(ctx, g)
+}
 
-	return b.Bytes(), nil
+func (g *ObjectMemberGroup) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeMembers(ctx, b, g, "Members", g.Members, false)
 }
 
 type ObjectProperty struct {
@@ -65,48 +86,41 @@ type ObjectProperty struct {
 	Value Expression
 	// Can not be set together with Value. Required if Value is not set.
 	Body []*ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ObjectProperty) isObjectMember() {}
 
 func (p *ObjectProperty) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-
-	modifiers, err := p.Modifiers.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithSuffix(modifiers, " ")
-
-	name, err := p.Name.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.Write(name)
+	return marshalNode(ctx, p)
+}
 
-	if p.Type != nil {
-		typ, err := p.Type.Marshal(ctx)
-		if err != nil {
-			return nil, err
+func (p *ObjectProperty) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeWithComments(ctx, b, p, p.LeadingComments, p.TrailingComment, func() error {
+		if err := writeFieldWithPrefixSuffix(ctx, b, "", p, "Modifiers", p.Modifiers, " "); err != nil {
+			return err
+		}
+		if err := writeField(ctx, b, p, "Name", p.Name); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(": ", typ)
-	}
 
-	if p.Value != nil {
-		val, err := p.Value.Marshal(ctx)
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, ": ", p, "Type", p.Type, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(" = ", val)
-	}
 
-	body, err := joinNodes(ctx, p.Body, " ")
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefix(" ", body)
+		if err := writeFieldWithPrefixSuffix(ctx, b, " = ", p, "Value", p.Value, ""); err != nil {
+			return err
+		}
 
-	return b.Bytes(), nil
+		return writeWithPrefixSuffix(b, " ", func() error {
+			return writeNodes(ctx, b, p, "Body", p.Body, " ")
+		}, "")
+	})
 }
 
 type ObjectMethod struct {
@@ -114,6 +128,12 @@ type ObjectMethod struct {
 	Signature *MethodSignature
 	// Required.
 	Value Expression
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ObjectMethod) isObjectMember() {}
@@ -121,19 +141,19 @@ func (m *ObjectMethod) isObjectMember() {}
 func (m *ObjectMethod) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	signature, err := m.Signature.Marshal(ctx)
+	signature, err := marshalField(ctx, m, "Signature", m.Signature)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(signature)
 
-	val, err := m.Value.Marshal(ctx)
+	val, err := marshalField(ctx, m, "Value", m.Value)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix(" = ", val)
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
 
 type ObjectEntry struct {
@@ -143,47 +163,59 @@ type ObjectEntry struct {
 	Value Expression
 	// Required if Value is unset. Can not be set together with Value.
 	Body []*ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ObjectEntry) isObjectMember() {}
 
 func (m *ObjectEntry) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
-
-	key, err := m.Key.Marshal(ctx)
-	if err != nil {
-		return nil, err
-	}
-	b.WriteWithPrefixSuffix("[", key, "]")
+	return marshalNode(ctx, m)
+}
 
-	if m.Value != nil {
-		val, err := m.Value.Marshal(ctx)
-		if err != nil {
-			return nil, err
+func (m *ObjectEntry) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeWithComments(ctx, b, m, m.LeadingComments, m.TrailingComment, func() error {
+		b.WriteString("[")
+		if err := writeField(ctx, b, m, "Key", m.Key); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(" = ", val)
-	}
+		b.WriteString("]")
 
-	if m.Body != nil {
-		body, err := joinNodes(ctx, m.Body, " ")
-		if err != nil {
-			return nil, err
+		if err := writeFieldWithPrefixSuffix(ctx, b, " = ", m, "Value", m.Value, ""); err != nil {
+			return err
 		}
-		b.WriteWithPrefix(" ", body)
-	}
 
-	return b.Bytes(), nil
+		return writeWithPrefixSuffix(b, " ", func() error {
+			return writeNodes(ctx, b, m, "Body", m.Body, " ")
+		}, "")
+	})
 }
 
 type ObjectElement struct {
 	// Required.
 	Value Expression
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ObjectElement) isObjectMember() {}
 
 func (m *ObjectElement) Marshal(ctx context.Context) ([]byte, error) {
-	return m.Value.Marshal(ctx)
+	return marshalNode(ctx, m)
+}
+
+func (m *ObjectElement) writeTo(ctx context.Context, b *bytesutil.Buffer) error {
+	return writeWithComments(ctx, b, m, m.LeadingComments, m.TrailingComment, func() error {
+		return writeField(ctx, b, m, "Value", m.Value)
+	})
 }
 
 type ObjectSpread struct {
@@ -191,6 +223,12 @@ type ObjectSpread struct {
 	Value Expression
 	// Optional.
 	Nullable bool
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ObjectSpread) isObjectMember() {}
@@ -204,13 +242,13 @@ func (m *ObjectSpread) Marshal(ctx context.Context) ([]byte, error) {
 		b.WriteString("...")
 	}
 
-	val, err := m.Value.Marshal(ctx)
+	val, err := marshalField(ctx, m, "Value", m.Value)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(val)
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
 
 type MemberPredicate struct {
@@ -220,35 +258,39 @@ type MemberPredicate struct {
 	Value Expression
 	// Required if Value is unset. Can not be set together with Value.
 	Body []*ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *MemberPredicate) isObjectMember() {}
 
 func (m *MemberPredicate) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
-	cond, err := m.Condition.Marshal(ctx)
+	cond, err := marshalField(ctx, m, "Condition", m.Condition)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix("[[", cond, "]]")
 
-	if m.Value != nil {
-		val, err := m.Value.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(" = ", val)
+	val, err := marshalOptionalField(ctx, m, "Value", m.Value)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(" = ", val)
 
 	if m.Body != nil {
-		body, err := joinNodes(ctx, m.Body, " ")
+		body, err := joinNodes(ctx, m, "Body", m.Body, " ")
 		if err != nil {
 			return nil, err
 		}
 		b.WriteWithPrefix(" ", body)
 	}
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
 
 type ForGenerator struct {
@@ -260,6 +302,12 @@ type ForGenerator struct {
 	Collection Expression
 	// Required.
 	Body *ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *ForGenerator) isObjectMember() {}
@@ -269,33 +317,31 @@ func (m *ForGenerator) Marshal(ctx context.Context) ([]byte, error) {
 
 	b.WriteString("for (")
 
-	if m.Key != nil {
-		key, err := m.Key.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithSuffix(key, ", ")
+	key, err := marshalOptionalField(ctx, m, "Key", m.Key)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithSuffix(key, ", ")
 
-	val, err := m.Value.Marshal(ctx)
+	val, err := marshalField(ctx, m, "Value", m.Value)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(val)
 
-	col, err := m.Collection.Marshal(ctx)
+	col, err := marshalField(ctx, m, "Collection", m.Collection)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix(" in ", col, ") ")
 
-	body, err := m.Body.Marshal(ctx)
+	body, err := marshalField(ctx, m, "Body", m.Body)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(body)
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
 
 type WhenGenerator struct {
@@ -305,6 +351,12 @@ type WhenGenerator struct {
 	Then *ObjectBody
 	// Optional.
 	Else *ObjectBody
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (m *WhenGenerator) isObjectMember() {}
@@ -312,25 +364,23 @@ func (m *WhenGenerator) isObjectMember() {}
 func (m *WhenGenerator) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	cond, err := m.Condition.Marshal(ctx)
+	cond, err := marshalField(ctx, m, "Condition", m.Condition)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix("when (", cond, ") ")
 
-	thenExpr, err := m.Then.Marshal(ctx)
+	thenExpr, err := marshalField(ctx, m, "Then", m.Then)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(thenExpr)
 
-	if m.Else != nil {
-		elseExpr, err := m.Else.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(" else ", elseExpr)
+	elseExpr, err := marshalOptionalField(ctx, m, "Else", m.Else)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(" else ", elseExpr)
 
-	return b.Bytes(), nil
+	return withComments(ctx, m, m.LeadingComments, m.TrailingComment, b.Bytes())
 }
@@ -125,6 +125,61 @@ func TestObjectMembersMarshal(t *testing.T) {
 				|  baz = 42
 			`),
 		},
+		{
+			name: "comments",
+			node: ObjectMembers{
+				&ObjectProperty{
+					LeadingComments: Comments{LineComment("TODO: tune.")},
+					Name:            "timeout",
+					Value:           IntExpression(30),
+				},
+				&ObjectEntry{
+					Key:             StringExpression("retry"),
+					Value:           IntExpression(5),
+					TrailingComment: "seconds",
+				},
+				LineComment("Standalone."),
+			},
+			res: stringsutil.StripMargin(`
+				|
+				|  // TODO: tune.
+				|  timeout = 30
+				|  ["retry"] = 5 // seconds
+				|
+				|  // Standalone.
+			`),
+		},
+		{
+			name: "groups",
+			node: ObjectMembers{
+				&ObjectProperty{Name: "name", Value: StringExpression("app")},
+				&ObjectMemberGroup{
+					Members: ObjectMembers{
+						&ObjectProperty{Name: "host", Value: StringExpression("localhost")},
+						&ObjectProperty{Name: "port", Value: IntExpression(8080)},
+					},
+				},
+				&ObjectMemberGroup{
+					Members: ObjectMembers{
+						LineComment("Limits."),
+						&ObjectProperty{Name: "timeout", Value: IntExpression(30)},
+					},
+				},
+				&ObjectProperty{Name: "debug", Value: ExpressionTrue},
+			},
+			res: stringsutil.StripMargin(`
+				|
+				|  name = "app"
+				|
+				|  host = "localhost"
+				|  port = 8080
+				|
+				|  // Limits.
+				|  timeout = 30
+				|
+				|  debug = true
+			`),
+		},
 		{
 			name: "mixed members",
 			node: ObjectMembers{
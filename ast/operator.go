@@ -43,3 +43,75 @@ const (
 	TypeOperatorIs TypeOperator = "is"
 	TypeOperatorAs TypeOperator = "as"
 )
+
+// Precedences of the expressions, from lowest to highest. Operands with a
+// lower precedence than their operator are printed in parentheses.
+const (
+	// Expressions that extend as far to the right as possible, such as if
+	// and let expressions.
+	precLowest = iota
+	precCoalesce
+	precPipe
+	precLogicalOr
+	precLogicalAnd
+	precBitwiseOr
+	precBitwiseAnd
+	precEquality
+	precTypeTest
+	precComparison
+	precAdditive
+	precMultiplicative
+	precExponent
+	precPrefix
+	precPostfix
+	precPrimary
+)
+
+// Precedence returns the precedence of op. Operators with a higher precedence
+// bind tighter, and invalid operators have the lowest one.
+func (op BinaryOperator) Precedence() int {
+	switch op {
+	case BinaryOperatorNullCoalesce:
+		return precCoalesce
+	case BinaryOperatorPipe:
+		return precPipe
+	case BinaryOperatorLogicalOr:
+		return precLogicalOr
+	case BinaryOperatorLogicalAnd:
+		return precLogicalAnd
+	case BinaryOperatorBitwiseOr:
+		return precBitwiseOr
+	case BinaryOperatorBitwiseAnd:
+		return precBitwiseAnd
+	case BinaryOperatorEqual, BinaryOperatorNotEqual:
+		return precEquality
+	case BinaryOperatorLessThan, BinaryOperatorLessThanOrEqual,
+		BinaryOperatorGreaterThan, BinaryOperatorGreaterThanOrEqual:
+		return precComparison
+	case BinaryOperatorPlus, BinaryOperatorMinus:
+		return precAdditive
+	case BinaryOperatorMultiply, BinaryOperatorDivide,
+		BinaryOperatorIntegerDivide, BinaryOperatorModulo:
+		return precMultiplicative
+	case BinaryOperatorExponent:
+		return precExponent
+	}
+	return precLowest
+}
+
+// OperandPrecedences returns the minimum precedences of the left and right
+// operands of op. ** and ?? are right-associative, the others are
+// left-associative.
+func (op BinaryOperator) OperandPrecedences() (left, right int) {
+	prec := op.Precedence()
+	if op == BinaryOperatorExponent || op == BinaryOperatorNullCoalesce {
+		return prec + 1, prec
+	}
+	return prec, prec + 1
+}
+
+// Precedence returns the precedence of the type operators, as in
+// BinaryOperator.Precedence.
+func (op TypeOperator) Precedence() int {
+	return precTypeTest
+}
@@ -11,6 +11,8 @@ type Parameter struct {
 	Name Identifier
 	// Optional.
 	Type Type
+
+	Span
 }
 
 var ParameterBlank = &Parameter{Name: IdentifierBlank}
@@ -18,19 +20,17 @@ var ParameterBlank = &Parameter{Name: IdentifierBlank}
 func (p *Parameter) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	name, err := p.Name.Marshal(ctx)
+	name, err := marshalField(ctx, p, "Name", p.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(name)
 
-	if p.Type != nil {
-		typ, err := p.Type.Marshal(ctx)
-		if err != nil {
-			return nil, err
-		}
-		b.WriteWithPrefix(": ", typ)
+	typ, err := marshalOptionalField(ctx, p, "Type", p.Type)
+	if err != nil {
+		return nil, err
 	}
+	b.WriteWithPrefix(": ", typ)
 
 	return b.Bytes(), nil
 }
@@ -38,7 +38,7 @@ func (p *Parameter) Marshal(ctx context.Context) ([]byte, error) {
 type Parameters []*Parameter
 
 func (p Parameters) Marshal(ctx context.Context) ([]byte, error) {
-	return joinNodes(ctx, p, ", ")
+	return joinNodes(ctx, p, "", p, ", ")
 }
 
 type TypeVariance string
@@ -51,6 +51,8 @@ const (
 type TypeParameter struct {
 	Variance TypeVariance
 	Name     Identifier
+
+	Span
 }
 
 func (t *TypeParameter) Marshal(ctx context.Context) ([]byte, error) {
@@ -60,7 +62,7 @@ func (t *TypeParameter) Marshal(ctx context.Context) ([]byte, error) {
 		b.WriteWithSuffix([]byte(t.Variance), " ")
 	}
 
-	name, err := t.Name.Marshal(ctx)
+	name, err := marshalField(ctx, t, "Name", t.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -72,13 +74,17 @@ func (t *TypeParameter) Marshal(ctx context.Context) ([]byte, error) {
 type TypeParameters []*TypeParameter
 
 func (t TypeParameters) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	if len(t) == 0 {
+		return nil, nil
+	}
 
-	params, err := joinNodes(ctx, t, ", ")
+	ctx, b := newDocBuffer(ctx)
+
+	params, err := marshalNodes(ctx, t, "", t)
 	if err != nil {
 		return nil, err
 	}
-	b.WriteWithPrefixSuffix("<", params, ">")
+	b.writeList("<", params, ">")
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
@@ -0,0 +1,69 @@
+package ast
+
+import "strconv"
+
+// Pos is a location in a Pkl source file.
+type Pos struct {
+	// Optional.
+	Filename string
+	// Byte offset, starting at 0.
+	Offset int
+	// Line number, starting at 1.
+	Line int
+	// Column number in bytes, starting at 1.
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the "file:line:column" format, omitting
+// the parts that are not known.
+func (p Pos) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// Span is the range of source code a node was parsed from. End is the
+// position immediately after the node.
+//
+// Span is embedded in every struct node. Nodes built by hand have the zero
+// Span, and scalar nodes (identifiers, literals, builtins, docs) can not
+// carry one; their position is covered by the span of the enclosing node.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// IsValid reports whether the span is known.
+func (s Span) IsValid() bool {
+	return s.Start.IsValid()
+}
+
+func (s Span) span() Span {
+	return s
+}
+
+type spanned interface {
+	span() Span
+}
+
+// Position returns the source span of the node, or the zero Span if it is
+// not known.
+func Position(n Node) Span {
+	if s, ok := n.(spanned); ok {
+		return s.span()
+	}
+	return Span{}
+}
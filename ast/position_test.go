@@ -0,0 +1,85 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosString(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  Pos
+		res  string
+	}{
+		{
+			name: "unknown",
+			pos:  Pos{},
+			res:  "-",
+		},
+		{
+			name: "without filename",
+			pos:  Pos{Offset: 10, Line: 2, Column: 4},
+			res:  "2:4",
+		},
+		{
+			name: "with filename",
+			pos:  Pos{Filename: "foo.pkl", Offset: 10, Line: 2, Column: 4},
+			res:  "foo.pkl:2:4",
+		},
+		{
+			name: "filename only",
+			pos:  Pos{Filename: "foo.pkl"},
+			res:  "foo.pkl",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, test.pos.String())
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	span := Span{
+		Start: Pos{Line: 1, Column: 1},
+		End:   Pos{Offset: 7, Line: 1, Column: 8},
+	}
+
+	tests := []struct {
+		name string
+		node Node
+		res  Span
+	}{
+		{
+			name: "struct node",
+			node: &ClassProperty{Name: "foo", Span: span},
+			res:  span,
+		},
+		{
+			name: "expression",
+			node: &MemberAccessExpression{Name: "foo", Span: span},
+			res:  span,
+		},
+		{
+			name: "built by hand",
+			node: &ObjectEntry{Key: StringExpression("foo")},
+			res:  Span{},
+		},
+		{
+			name: "scalar node",
+			node: IntExpression(42),
+			res:  Span{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := Position(test.node)
+
+			assert.Equal(t, test.res, res)
+			assert.Equal(t, test.res.IsValid(), res.IsValid())
+		})
+	}
+}
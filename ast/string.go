@@ -0,0 +1,219 @@
+package ast
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// quote returns s as a Pkl string literal, between custom delimiters with the
+// given number of # characters, if any. Only the escape sequences of Pkl are
+// used: \t, \n, \r, \", \\ and \u{...} for the characters that are not
+// printable. Backslashes are always escaped, so "\(" in s is not read as the
+// start of an interpolation. Invalid UTF-8 is replaced with U+FFFD.
+//
+// Between custom delimiters, quotes and backslashes are not escaped, so s
+// must not have them followed by pounds # characters. See delimiterPounds.
+func quote(s string, pounds int) string {
+	w := newStringWriter(pounds, false, "")
+	w.Grow(len(s) + 2 + 2*pounds)
+	w.writeText(s)
+	return w.close()
+}
+
+// quoteMultiline returns s as a Pkl multi-line string literal, between custom
+// delimiters with the given number of # characters, if any. Each line of s,
+// and the closing delimiter, is indented with indent, except for the empty
+// lines. Tabs are written as is, while carriage returns and the characters
+// that are not printable are escaped as in quote.
+//
+// Without custom delimiters, backslashes are escaped, and so is every third
+// quote in a row, so that s has no """ ending the string.
+func quoteMultiline(s string, pounds int, indent string) string {
+	w := newStringWriter(pounds, true, indent)
+	w.Grow(len(s) + 8 + 2*pounds + (strings.Count(s, "\n")+2)*len(indent))
+	w.writeText(s)
+	return w.close()
+}
+
+// stringWriter writes a Pkl string literal, made of text and interpolated
+// expressions. It is opened by newStringWriter and closed by close.
+type stringWriter struct {
+	strings.Builder
+	pounds    int
+	multiline bool
+	// The indentation of the lines of a multi-line string.
+	indent string
+	escape string
+	// Whether the current line of a multi-line string has no text yet, so
+	// its indentation was not written.
+	lineStart bool
+	// The number of unescaped quotes in a row at the end of the text.
+	quotes int
+}
+
+func newStringWriter(pounds int, multiline bool, indent string) *stringWriter {
+	w := &stringWriter{
+		pounds:    pounds,
+		multiline: multiline,
+		indent:    indent,
+		escape:    `\` + strings.Repeat("#", pounds),
+	}
+	w.WriteString(strings.Repeat("#", pounds))
+	if multiline {
+		w.WriteString(`"""`)
+		w.newline()
+	} else {
+		w.WriteByte('"')
+	}
+	return w
+}
+
+func (w *stringWriter) newline() {
+	w.WriteByte('\n')
+	w.lineStart = true
+	w.quotes = 0
+}
+
+func (w *stringWriter) startLine() {
+	if w.lineStart {
+		w.WriteString(w.indent)
+		w.lineStart = false
+	}
+}
+
+// writeText writes s escaped as the text of the string.
+func (w *stringWriter) writeText(s string) {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		if r == '\n' && w.multiline {
+			w.newline()
+			continue
+		}
+		w.startLine()
+
+		switch {
+		case r == '"' && w.pounds == 0 && w.multiline:
+			if w.quotes == 2 {
+				w.WriteString(`\"`)
+				w.quotes = 0
+				continue
+			}
+			w.WriteByte('"')
+			w.quotes++
+			continue
+		case (r == '"' || r == '\\') && w.pounds == 0:
+			w.WriteByte('\\')
+			w.WriteRune(r)
+		case r == '\t' && w.multiline:
+			w.WriteByte('\t')
+		default:
+			writeRune(&w.Builder, r, w.escape)
+		}
+		w.quotes = 0
+	}
+}
+
+// writeInterpolation writes the source code of an interpolated expression.
+func (w *stringWriter) writeInterpolation(expr []byte) {
+	w.startLine()
+	w.WriteString(w.escape)
+	w.WriteByte('(')
+	w.Write(expr)
+	w.WriteByte(')')
+	w.quotes = 0
+}
+
+// close writes the closing delimiter and returns the string literal.
+func (w *stringWriter) close() string {
+	if w.multiline {
+		w.newline()
+		w.startLine()
+		w.WriteString(`"""`)
+	} else {
+		w.WriteByte('"')
+	}
+	w.WriteString(strings.Repeat("#", w.pounds))
+	return w.String()
+}
+
+// writeRune writes r to a string literal, escaping it with the given escape
+// prefix if it is a line break, a tab or not printable.
+func writeRune(b *strings.Builder, r rune, escape string) {
+	switch {
+	case r == '\t':
+		b.WriteString(escape + "t")
+	case r == '\n':
+		b.WriteString(escape + "n")
+	case r == '\r':
+		b.WriteString(escape + "r")
+	case unicode.IsPrint(r):
+		b.WriteRune(r)
+	default:
+		b.WriteString(escape + "u{")
+		b.WriteString(strings.ToUpper(strconv.FormatInt(int64(r), 16)))
+		b.WriteByte('}')
+	}
+}
+
+// delimiterPounds returns the minimum number of # characters of custom
+// delimiters around s, so that no quote in s ends the string, and no
+// backslash starts an escape sequence.
+func delimiterPounds(s string) int {
+	pounds := 1
+	for i := range len(s) {
+		if s[i] != '"' && s[i] != '\\' {
+			continue
+		}
+		n := 0
+		for i+1+n < len(s) && s[i+1+n] == '#' {
+			n++
+		}
+		pounds = max(pounds, n+1)
+	}
+	return pounds
+}
+
+// stringPounds returns the number of # characters of the custom delimiters
+// of a string literal with the given text parts, if any. It is raised to the
+// minimum needed for the text not to end the string. If PrintConfig
+// CustomDelimiters is set and the text has quotes or backslashes that would
+// be escaped, it uses custom delimiters so they need no escaping.
+func stringPounds(ctx context.Context, texts []string, pounds int, multiline bool) int {
+	custom := pounds > 0
+	if !custom && getPrintConfig(ctx).CustomDelimiters {
+		for _, s := range texts {
+			if strings.Contains(s, `\`) || (multiline && strings.Contains(s, `"""`)) || (!multiline && strings.Contains(s, `"`)) {
+				custom = true
+				break
+			}
+		}
+	}
+	if !custom {
+		return 0
+	}
+	for _, s := range texts {
+		pounds = max(pounds, delimiterPounds(s))
+	}
+	return max(pounds, 1)
+}
+
+// quoteString returns s as a Pkl string literal. If PrintConfig
+// CustomDelimiters is set and s has quotes or backslashes, it uses custom
+// delimiters so they need no escaping.
+func quoteString(ctx context.Context, s string) string {
+	return quote(s, stringPounds(ctx, []string{s}, 0, false))
+}
+
+// quoteMultilineString returns s as a Pkl multi-line string literal indented
+// to the current indentation level. If PrintConfig CustomDelimiters is set and
+// s has backslashes or """, it uses custom delimiters so they need no
+// escaping.
+func quoteMultilineString(ctx context.Context, s string, pounds int) string {
+	pounds = stringPounds(ctx, []string{s}, pounds, true)
+	return quoteMultiline(s, pounds, indentation(raiseIndentLevel(ctx)))
+}
@@ -0,0 +1,183 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		pounds int
+		res    string
+	}{
+		{
+			name: "empty",
+			s:    "",
+			res:  `""`,
+		},
+		{
+			name: "plain",
+			s:    "foo bar",
+			res:  `"foo bar"`,
+		},
+		{
+			name: "escapes",
+			s:    "a\tb\nc\rd\"e\\f",
+			res:  `"a\tb\nc\rd\"e\\f"`,
+		},
+		{
+			name: "interpolation",
+			s:    `\(foo)`,
+			res:  `"\\(foo)"`,
+		},
+		{
+			name: "control characters",
+			s:    "\x00\a\v\x7f",
+			res:  `"\u{0}\u{7}\u{B}\u{7F}"`,
+		},
+		{
+			name: "unicode",
+			s:    "ação 😀",
+			res:  `"ação 😀"`,
+		},
+		{
+			name: "non-printable unicode",
+			s:    "\u200b\U000E0001",
+			res:  `"\u{200B}\u{E0001}"`,
+		},
+		{
+			name: "invalid utf-8",
+			s:    "a\xffb",
+			res:  `"a` + "�" + `b"`,
+		},
+		{
+			name:   "custom delimiters",
+			s:      `C:\path "quoted"`,
+			pounds: 1,
+			res:    `#"C:\path "quoted""#`,
+		},
+		{
+			name:   "custom delimiters escapes",
+			s:      "a\tb\n\x00",
+			pounds: 2,
+			res:    `##"a\##tb\##n\##u{0}"##`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, quote(test.s, test.pounds))
+		})
+	}
+}
+
+func TestQuoteMultiline(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		pounds int
+		indent string
+		res    string
+	}{
+		{
+			name: "empty",
+			s:    "",
+			res:  "\"\"\"\n\n\"\"\"",
+		},
+		{
+			name:   "lines",
+			s:      "foo\n  bar",
+			indent: "  ",
+			res: stringsutil.StripMargin(`
+				|"""
+				|  foo
+				|    bar
+				|  """
+			`),
+		},
+		{
+			name:   "empty lines are not indented",
+			s:      "foo\n\nbar\n",
+			indent: "  ",
+			res:    "\"\"\"\n  foo\n\n  bar\n\n  \"\"\"",
+		},
+		{
+			name: "escapes",
+			s:    "a\tb\rc\\d\x00",
+			res: stringsutil.StripMargin(`
+				|"""
+				|a` + "\t" + `b\rc\\d\u{0}
+				|"""
+			`),
+		},
+		{
+			name: "quotes",
+			s:    `"a" "" """ """"""`,
+			res: stringsutil.StripMargin(`
+				|"""
+				|"a" "" ""\" ""\"""\"
+				|"""
+			`),
+		},
+		{
+			name:   "custom delimiters",
+			s:      `C:\path """quoted"""` + "\r",
+			pounds: 1,
+			res: stringsutil.StripMargin(`
+				|#"""
+				|C:\path """quoted"""\#r
+				|"""#
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, quoteMultiline(test.s, test.pounds, test.indent))
+		})
+	}
+}
+
+func TestDelimiterPounds(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		res  int
+	}{
+		{
+			name: "plain",
+			s:    "foo",
+			res:  1,
+		},
+		{
+			name: "backslash",
+			s:    `\d+`,
+			res:  1,
+		},
+		{
+			name: "quote followed by pound",
+			s:    `"#`,
+			res:  2,
+		},
+		{
+			name: "backslash followed by pounds",
+			s:    `a\##(b)"#`,
+			res:  3,
+		},
+		{
+			name: "pounds alone",
+			s:    `###`,
+			res:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, delimiterPounds(test.s))
+		})
+	}
+}
@@ -2,7 +2,7 @@ package ast
 
 import (
 	"context"
-	"strconv"
+	"slices"
 
 	"github.com/pauloborges/balsamic/internal/bytesutil"
 )
@@ -30,43 +30,44 @@ type StringLiteralType string
 
 func (t StringLiteralType) isType() {}
 
-func (t StringLiteralType) Marshal(_ context.Context) ([]byte, error) {
-	return []byte(strconv.Quote(string(t))), nil
+func (t StringLiteralType) Marshal(ctx context.Context) ([]byte, error) {
+	return []byte(quoteString(ctx, string(t))), nil
 }
 
 type DeclaredType struct {
 	Name           QualifiedIdentifier
 	TypeParameters []Type
+
+	Span
 }
 
 func (t *DeclaredType) isType() {}
 
 func (t *DeclaredType) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
 
-	name, err := t.Name.Marshal(ctx)
+	name, err := marshalField(ctx, t, "Name", t.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(name)
 
 	if len(t.TypeParameters) > 0 {
-		params, err := joinNodes(ctx, t.TypeParameters, ", ")
+		params, err := marshalNodes(ctx, t, "TypeParameters", t.TypeParameters)
 		if err != nil {
 			return nil, err
 		}
-
-		b.WriteRune('<')
-		b.Write(params)
-		b.WriteByte('>')
+		b.writeList("<", params, ">")
 	}
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 type ParenthesizedType struct {
 	// Required.
 	Type Type
+
+	Span
 }
 
 func (t *ParenthesizedType) isType() {}
@@ -74,7 +75,7 @@ func (t *ParenthesizedType) isType() {}
 func (t *ParenthesizedType) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	typ, err := t.Type.Marshal(ctx)
+	typ, err := marshalField(ctx, t, "Type", t.Type)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +87,8 @@ func (t *ParenthesizedType) Marshal(ctx context.Context) ([]byte, error) {
 type NullableType struct {
 	// Required.
 	Type Type
+
+	Span
 }
 
 func (t *NullableType) isType() {}
@@ -93,7 +96,7 @@ func (t *NullableType) isType() {}
 func (t *NullableType) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	typ, err := t.Type.Marshal(ctx)
+	typ, err := marshalField(ctx, t, "Type", t.Type)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +110,8 @@ type ConstrainedType struct {
 	Type Type
 	// Optional.
 	Constraints Expressions
+
+	Span
 }
 
 func (t *ConstrainedType) isType() {}
@@ -114,13 +119,13 @@ func (t *ConstrainedType) isType() {}
 func (t *ConstrainedType) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	typ, err := t.Type.Marshal(ctx)
+	typ, err := marshalField(ctx, t, "Type", t.Type)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(typ)
 
-	constraints, err := t.Constraints.Marshal(ctx)
+	constraints, err := marshalField(ctx, t, "Constraints", t.Constraints)
 	if err != nil {
 		return nil, err
 	}
@@ -134,34 +139,47 @@ type UnionType struct {
 	Members []Type
 	// Optional.
 	Default Type
+	// Number of the last members printed after Default, as in *"a" | "b"
+	// for 1. Zero prints Default after all the members.
+	MembersAfterDefault int
+
+	Span
 }
 
 func (t *UnionType) isType() {}
 
 func (t *UnionType) Marshal(ctx context.Context) ([]byte, error) {
-	var b bytesutil.Buffer
+	ctx, b := newDocBuffer(ctx)
 
-	members, err := joinNodes(ctx, t.Members, " | ")
+	members, err := marshalNodes(ctx, t, "Members", t.Members)
 	if err != nil {
 		return nil, err
 	}
-	b.Write(members)
 
-	if t.Default != nil {
-		dflt, err := t.Default.Marshal(ctx)
+	if !isNil(t.Default) {
+		dflt, err := marshalField(ctx, t, "Default", t.Default)
 		if err != nil {
 			return nil, err
 		}
 
-		if len(t.Members) > 0 {
-			b.WriteString(" | *")
-		} else {
-			b.WriteRune('*')
+		at := len(members) - min(max(t.MembersAfterDefault, 0), len(members))
+		members = slices.Insert(members, at, append([]byte("*"), dflt...))
+	}
+
+	// If the members do not fit in the line, each goes in its own line.
+	b.StartGroup()
+	b.Indent()
+	for i, member := range members {
+		if i > 0 {
+			b.Line()
+			b.WriteString("| ")
 		}
-		b.Write(dflt)
+		b.Write(member)
 	}
+	b.Dedent()
+	b.EndGroup()
 
-	return b.Bytes(), nil
+	return b.Doc(), nil
 }
 
 type FunctionLiteralType struct {
@@ -169,6 +187,8 @@ type FunctionLiteralType struct {
 	Parameters []Type
 	// Required.
 	Result Type
+
+	Span
 }
 
 func (t *FunctionLiteralType) isType() {}
@@ -176,13 +196,13 @@ func (t *FunctionLiteralType) isType() {}
 func (t *FunctionLiteralType) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	params, err := joinNodes(ctx, t.Parameters, ", ")
+	params, err := joinNodes(ctx, t, "Parameters", t.Parameters, ", ")
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefixSuffix("(", params, ")")
 
-	result, err := t.Result.Marshal(ctx)
+	result, err := marshalField(ctx, t, "Result", t.Result)
 	if err != nil {
 		return nil, err
 	}
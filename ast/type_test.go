@@ -172,7 +172,7 @@ func TestConstrainedTypeMarshal(t *testing.T) {
 		{
 			name: "constrained type",
 			node: ConstrainedType{
-				Type: &DeclaredType{"String", nil},
+				Type: &DeclaredType{Name: "String"},
 				Constraints: Expressions{
 					&BinaryExpression{
 						Operator: BinaryOperatorLessThanOrEqual,
@@ -224,6 +224,27 @@ func TestUnionTypeMarshal(t *testing.T) {
 			},
 			res: `Int | String | *"foobar"`,
 		},
+		{
+			name: "default between members",
+			node: &UnionType{
+				Members: []Type{
+					&DeclaredType{Name: "Int"},
+					&DeclaredType{Name: "String"},
+				},
+				Default:             StringLiteralType("foobar"),
+				MembersAfterDefault: 1,
+			},
+			res: `Int | *"foobar" | String`,
+		},
+		{
+			name: "default first",
+			node: &UnionType{
+				Members:             []Type{&DeclaredType{Name: "Int"}},
+				Default:             StringLiteralType("foobar"),
+				MembersAfterDefault: 1,
+			},
+			res: `*"foobar" | Int`,
+		},
 	}
 
 	for _, test := range tests {
@@ -19,6 +19,12 @@ type TypeAlias struct {
 	Parameters TypeParameters
 	// Required.
 	Type Type
+	// Optional.
+	LeadingComments Comments
+	// Optional.
+	TrailingComment LineComment
+
+	Span
 }
 
 func (t *TypeAlias) isModuleMember() {}
@@ -26,41 +32,41 @@ func (t *TypeAlias) isModuleMember() {}
 func (t *TypeAlias) Marshal(ctx context.Context) ([]byte, error) {
 	var b bytesutil.Buffer
 
-	docs, err := t.Docs.Marshal(ctx)
+	docs, err := marshalField(ctx, t, "Docs", t.Docs)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(docs, newlineWithIndentation(ctx))
 
-	annotations, err := t.Annotations.Marshal(ctx)
+	annotations, err := marshalField(ctx, t, "Annotations", t.Annotations)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(annotations, newlineWithIndentation(ctx))
 
-	modifiers, err := t.Modifiers.Marshal(ctx)
+	modifiers, err := marshalField(ctx, t, "Modifiers", t.Modifiers)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithSuffix(modifiers, " ")
 
-	name, err := t.Name.Marshal(ctx)
+	name, err := marshalField(ctx, t, "Name", t.Name)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix("typealias ", name)
 
-	params, err := t.Parameters.Marshal(ctx)
+	params, err := marshalField(ctx, t, "Parameters", t.Parameters)
 	if err != nil {
 		return nil, err
 	}
 	b.Write(params)
 
-	typ, err := t.Type.Marshal(ctx)
+	typ, err := marshalField(ctx, t, "Type", t.Type)
 	if err != nil {
 		return nil, err
 	}
 	b.WriteWithPrefix(" = ", typ)
 
-	return b.Bytes(), nil
+	return withComments(ctx, t, t.LeadingComments, t.TrailingComment, b.Bytes())
 }
@@ -0,0 +1,530 @@
+package ast
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a violation of the rules of the fields of a node, such
+// as a required field that is not set.
+type ValidationError struct {
+	// The path to the offending field from the validated node, as in
+	// Members[2].Body.Members[5].Value. Empty for the validated node itself.
+	Path string
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// ValidationErrors are all the violations found by Validate, in the order of
+// the fields of the nodes.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate checks that the fields of n and of all the nodes in it follow the
+// rules in their comments: required fields are set, fields that can not be
+// set together are not, identifiers and enumerations have valid values, and
+// modifiers are legal on their declarations, as in Modifiers.Validate.
+// Amending modules can only amend the members of their parent, so they can
+// not declare classes, type aliases, nor properties with types or methods
+// unless local. It returns ValidationErrors with all the violations, or nil
+// if there are none.
+func Validate(n Node) error {
+	var v validator
+	v.required("", n)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// Validate checks the module and all the nodes in it. See Validate.
+func (m *Module) Validate() error {
+	return Validate(m)
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) errorf(path, msg string) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Msg: msg})
+}
+
+func field(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// isNil reports whether n is not set, including a nil pointer to a node.
+func isNil(n Node) bool {
+	if n == nil {
+		return true
+	}
+	val := reflect.ValueOf(n)
+	return val.Kind() == reflect.Pointer && val.IsNil()
+}
+
+// required checks the node of a required field.
+func (v *validator) required(path string, n Node) {
+	if isNil(n) {
+		v.errorf(path, "is required")
+		return
+	}
+	v.node(path, n)
+}
+
+// optional checks the node of an optional field, if set.
+func (v *validator) optional(path string, n Node) {
+	if !isNil(n) {
+		v.node(path, n)
+	}
+}
+
+// nodes checks each of the nodes of a list, none of which can be nil.
+func nodes[N Node](v *validator, path string, list []N) {
+	for i, n := range list {
+		v.required(index(path, i), n)
+	}
+}
+
+// nonEmpty checks a required list of nodes.
+func nonEmpty[N Node](v *validator, path string, list []N) {
+	if len(list) == 0 {
+		v.errorf(path, "is required")
+		return
+	}
+	nodes(v, path, list)
+}
+
+func (v *validator) identifier(path string, name Identifier) {
+	if name == "" {
+		v.errorf(path, "is required")
+		return
+	}
+	if msg := identifierError(string(name)); msg != "" {
+		v.errorf(path, msg)
+	}
+}
+
+func (v *validator) qualifiedIdentifier(path string, name QualifiedIdentifier) {
+	if name == "" {
+		v.errorf(path, "is required")
+		return
+	}
+	for part := range strings.SplitSeq(string(name), ".") {
+		if msg := identifierError(part); msg != "" {
+			v.errorf(path, msg)
+			return
+		}
+	}
+}
+
+func (v *validator) nonEmptyString(path, s string) {
+	if s == "" {
+		v.errorf(path, "is required")
+	}
+}
+
+// oneOf checks that value is one of the valid values of an enumeration, or
+// empty if the field is optional.
+func oneOf[T ~string](v *validator, path string, value T, optional bool, valid ...T) {
+	if value == "" && optional {
+		return
+	}
+	if value == "" {
+		v.errorf(path, "is required")
+		return
+	}
+	if !slices.Contains(valid, value) {
+		v.errorf(path, "invalid value "+quote(string(value), 0))
+	}
+}
+
+func (v *validator) node(path string, n Node) {
+	switch n := n.(type) {
+	// Modules.
+	case *Module:
+		if n.Name == "" && n.ParentRelationship == "" {
+			v.errorf(field(path, "Name"), "is required if ParentRelationship is not set")
+		} else if n.Name != "" {
+			v.qualifiedIdentifier(field(path, "Name"), n.Name)
+		}
+		oneOf(v, field(path, "ParentRelationship"), n.ParentRelationship, true,
+			ModuleRelationshipAmends, ModuleRelationshipExtends)
+		if n.ParentRelationship != "" && n.ParentName == "" {
+			v.errorf(field(path, "ParentName"), "is required if ParentRelationship is set")
+		}
+		if n.ParentRelationship == "" && n.ParentName != "" {
+			v.errorf(field(path, "ParentRelationship"), "is required if ParentName is set")
+		}
+		nodes(v, field(path, "Annotations"), n.Annotations)
+		if n.ParentRelationship == ModuleRelationshipAmends {
+			v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationAmendingModule)
+		} else {
+			v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationModule)
+		}
+		nodes(v, field(path, "Imports"), n.Imports)
+		if n.ParentRelationship == ModuleRelationshipAmends {
+			v.amendingMembers(field(path, "Members"), n.Members)
+		}
+		nodes(v, field(path, "Members"), n.Members)
+	case *ImportClause:
+		v.nonEmptyString(field(path, "Path"), n.Path)
+		if n.Alias != "" {
+			v.identifier(field(path, "Alias"), Identifier(n.Alias))
+		}
+	case ImportClauses:
+		nodes(v, path, n)
+	case ModuleMembers:
+		nodes(v, path, n)
+	case *ModuleMemberGroup:
+		nonEmpty(v, field(path, "Members"), n.Members)
+	case *Annotation:
+		v.qualifiedIdentifier(field(path, "Name"), n.Name)
+		v.optional(field(path, "Body"), n.Body)
+	case Annotations:
+		nodes(v, path, n)
+
+	// Classes and type aliases.
+	case *Class:
+		nodes(v, field(path, "Annotations"), n.Annotations)
+		v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationClass)
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "TypeParameters"), n.TypeParameters)
+		if n.ParentName != "" {
+			v.qualifiedIdentifier(field(path, "ParentName"), n.ParentName)
+		} else if len(n.ParentTypeParameters) > 0 {
+			v.errorf(field(path, "ParentName"), "is required if ParentTypeParameters is set")
+		}
+		nodes(v, field(path, "ParentTypeParameters"), n.ParentTypeParameters)
+		nodes(v, field(path, "Members"), n.Members)
+	case *ClassMemberGroup:
+		nonEmpty(v, field(path, "Members"), n.Members)
+	case *ClassProperty:
+		nodes(v, field(path, "Annotations"), n.Annotations)
+		v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationClassProperty)
+		v.identifier(field(path, "Name"), n.Name)
+		if isNil(n.Type) && isNil(n.Expression) && isNil(n.Body) {
+			v.errorf(field(path, "Expression"), "is required if Type and Body are not set")
+		}
+		if !isNil(n.Body) {
+			v.notWithBody(field(path, "Type"), n.Type)
+			v.notWithBody(field(path, "Expression"), n.Expression)
+		}
+		v.optional(field(path, "Type"), n.Type)
+		v.optional(field(path, "Expression"), n.Expression)
+		v.optional(field(path, "Body"), n.Body)
+	case *MethodSignature:
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "TypeParameters"), n.TypeParameters)
+		nodes(v, field(path, "Parameters"), n.Parameters)
+		v.optional(field(path, "Result"), n.Result)
+	case *ClassMethod:
+		nodes(v, field(path, "Annotations"), n.Annotations)
+		v.required(field(path, "Signature"), n.Signature)
+		if n.Signature != nil {
+			v.modifiers(field(path, "Signature.Modifiers"), n.Signature.Modifiers, DeclarationMethod)
+		}
+		v.optional(field(path, "Implementation"), n.Implementation)
+	case *TypeAlias:
+		nodes(v, field(path, "Annotations"), n.Annotations)
+		v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationTypeAlias)
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "Parameters"), n.Parameters)
+		v.required(field(path, "Type"), n.Type)
+	case *Parameter:
+		v.identifier(field(path, "Name"), n.Name)
+		v.optional(field(path, "Type"), n.Type)
+	case Parameters:
+		nodes(v, path, n)
+	case *TypeParameter:
+		oneOf(v, field(path, "Variance"), n.Variance, true, VarianceIn, VarianceOut)
+		v.identifier(field(path, "Name"), n.Name)
+	case TypeParameters:
+		nodes(v, path, n)
+
+	// Objects.
+	case *ObjectBody:
+		nodes(v, field(path, "Parameters"), n.Parameters)
+		nodes(v, field(path, "Members"), n.Members)
+	case ObjectMembers:
+		nodes(v, path, n)
+	case *ObjectMemberGroup:
+		nonEmpty(v, field(path, "Members"), n.Members)
+	case *ObjectProperty:
+		v.modifiers(field(path, "Modifiers"), n.Modifiers, DeclarationObjectMember)
+		v.identifier(field(path, "Name"), n.Name)
+		if isNil(n.Value) && n.Body == nil {
+			v.errorf(field(path, "Value"), "is required if Body is not set")
+		}
+		if n.Body != nil {
+			v.notWithBody(field(path, "Type"), n.Type)
+			v.notWithBody(field(path, "Value"), n.Value)
+		}
+		v.optional(field(path, "Type"), n.Type)
+		v.optional(field(path, "Value"), n.Value)
+		nodes(v, field(path, "Body"), n.Body)
+	case *ObjectMethod:
+		v.required(field(path, "Signature"), n.Signature)
+		if n.Signature != nil {
+			v.modifiers(field(path, "Signature.Modifiers"), n.Signature.Modifiers, DeclarationObjectMember)
+		}
+		v.required(field(path, "Value"), n.Value)
+	case *ObjectEntry:
+		v.required(field(path, "Key"), n.Key)
+		v.valueOrBody(path, n.Value, n.Body)
+	case *ObjectElement:
+		v.required(field(path, "Value"), n.Value)
+	case *ObjectSpread:
+		v.required(field(path, "Value"), n.Value)
+	case *MemberPredicate:
+		v.required(field(path, "Condition"), n.Condition)
+		v.valueOrBody(path, n.Value, n.Body)
+	case *ForGenerator:
+		v.optional(field(path, "Key"), n.Key)
+		v.required(field(path, "Value"), n.Value)
+		v.required(field(path, "Collection"), n.Collection)
+		v.required(field(path, "Body"), n.Body)
+	case *WhenGenerator:
+		v.required(field(path, "Condition"), n.Condition)
+		v.required(field(path, "Then"), n.Then)
+		v.optional(field(path, "Else"), n.Else)
+
+	// Expressions.
+	case Expressions:
+		nodes(v, path, n)
+	case *FormattedIntExpression:
+		if _, ok := radixPrefixes[n.Radix]; !ok && n.Radix != 0 && n.Radix != IntRadixDecimal {
+			v.errorf(field(path, "Radix"), "invalid radix "+strconv.Itoa(int(n.Radix)))
+		}
+	case *RawStringExpression:
+		v.pounds(path, n.Pounds)
+	case *MultiLineStringExpression:
+		v.pounds(path, n.Pounds)
+	case *InterpolatedStringExpression:
+		if len(n.Parts) == 0 {
+			v.errorf(field(path, "Parts"), "is required")
+		}
+		for i, part := range n.Parts {
+			partPath := index(field(path, "Parts"), i)
+			switch part := part.(type) {
+			case StringText:
+			case *StringInterpolation:
+				if part == nil {
+					v.errorf(partPath, "is required")
+					continue
+				}
+				v.required(field(partPath, "Expression"), part.Expression)
+			default:
+				v.errorf(partPath, "is required")
+			}
+		}
+		v.pounds(path, n.Pounds)
+	case *PrefixUnaryExpression:
+		oneOf(v, field(path, "Operator"), n.Operator, false, UnaryOperandMinus, UnaryOperandLogicalNot)
+		v.required(field(path, "Operand"), n.Operand)
+	case *PostfixUnaryExpression:
+		oneOf(v, field(path, "Operator"), n.Operator, false, PostfixUnaryOperandNonNullAssertion)
+		v.required(field(path, "Operand"), n.Operand)
+	case *BinaryExpression:
+		if n.Operator == "" {
+			v.errorf(field(path, "Operator"), "is required")
+		} else if n.Operator.Precedence() == precLowest {
+			v.errorf(field(path, "Operator"), "invalid value "+quote(string(n.Operator), 0))
+		}
+		v.required(field(path, "Left"), n.Left)
+		v.required(field(path, "Right"), n.Right)
+	case *TypeExpression:
+		oneOf(v, field(path, "Operator"), n.Operator, false, TypeOperatorIs, TypeOperatorAs)
+		v.required(field(path, "Expression"), n.Expression)
+		v.required(field(path, "Type"), n.Type)
+	case *MemberAccessExpression:
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "Arguments"), n.Arguments)
+	case *QualifiedMemberAccessExpression:
+		v.required(field(path, "Receiver"), n.Receiver)
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "Arguments"), n.Arguments)
+	case *SuperAccessExpression:
+		v.identifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "Arguments"), n.Arguments)
+	case *SubscriptExpression:
+		v.required(field(path, "Receiver"), n.Receiver)
+		v.required(field(path, "Subscript"), n.Subscript)
+	case *SuperSubscriptExpression:
+		v.required(field(path, "Subscript"), n.Subscript)
+	case *ParenthesizedExpression:
+		v.required(field(path, "Expression"), n.Expression)
+	case *NewExpression:
+		v.optional(field(path, "Type"), n.Type)
+		v.required(field(path, "Body"), n.Body)
+	case *AmendExpression:
+		v.required(field(path, "Parent"), n.Parent)
+		v.required(field(path, "Body"), n.Body)
+	case *IfExpression:
+		v.required(field(path, "Condition"), n.Condition)
+		v.required(field(path, "Then"), n.Then)
+		v.required(field(path, "Else"), n.Else)
+	case *ImportExpression:
+		v.nonEmptyString(field(path, "Path"), n.Path)
+	case *LetExpression:
+		v.required(field(path, "Name"), n.Name)
+		v.required(field(path, "Value"), n.Value)
+		v.required(field(path, "Expression"), n.Expression)
+	case *FunctionLiteralExpression:
+		nodes(v, field(path, "Parameters"), n.Parameters)
+		v.required(field(path, "Body"), n.Body)
+	case *ReadExpression:
+		oneOf(v, field(path, "Variant"), n.Variant, true, ReadVariantNullable, ReadVariantGlob)
+		v.required(field(path, "Value"), n.Value)
+	case *ThrowExpression:
+		v.required(field(path, "Value"), n.Value)
+	case *TraceExpression:
+		v.required(field(path, "Value"), n.Value)
+
+	// Types.
+	case *DeclaredType:
+		v.qualifiedIdentifier(field(path, "Name"), n.Name)
+		nodes(v, field(path, "TypeParameters"), n.TypeParameters)
+	case *ParenthesizedType:
+		v.required(field(path, "Type"), n.Type)
+	case *NullableType:
+		v.required(field(path, "Type"), n.Type)
+	case *ConstrainedType:
+		v.required(field(path, "Type"), n.Type)
+		nodes(v, field(path, "Constraints"), n.Constraints)
+	case *UnionType:
+		nonEmpty(v, field(path, "Members"), n.Members)
+		v.optional(field(path, "Default"), n.Default)
+		switch {
+		case n.MembersAfterDefault < 0:
+			v.errorf(field(path, "MembersAfterDefault"), "can not be negative")
+		case n.MembersAfterDefault > len(n.Members):
+			v.errorf(field(path, "MembersAfterDefault"), "can not be greater than the number of members")
+		case n.MembersAfterDefault > 0 && isNil(n.Default):
+			v.errorf(field(path, "MembersAfterDefault"), "can not be set if Default is not set")
+		}
+	case *FunctionLiteralType:
+		nodes(v, field(path, "Parameters"), n.Parameters)
+		v.required(field(path, "Result"), n.Result)
+	}
+}
+
+// modifiers checks that the modifiers are legal on a declaration of kind d.
+func (v *validator) modifiers(path string, m Modifiers, d Declaration) {
+	for i, modifier := range m {
+		modifierPath := index(path, i)
+		switch {
+		case !slices.Contains(modifierOrder, modifier):
+			v.errorf(modifierPath, "invalid value "+quote(string(modifier), 0))
+		case !slices.Contains(declarationModifiers[d], modifier):
+			v.errorf(modifierPath, quote(string(modifier), 0)+" is not a valid "+string(d)+" modifier")
+		case slices.Contains(m[:i], modifier):
+			v.errorf(modifierPath, "duplicated modifier "+quote(string(modifier), 0))
+		default:
+			for _, pair := range conflictingModifiers {
+				if other, ok := conflictingModifier(pair, modifier); ok && slices.Contains(m[:i], other) {
+					v.errorf(modifierPath, "can not be set together with "+quote(string(other), 0))
+				}
+			}
+		}
+	}
+}
+
+// conflictingModifier returns the modifier of pair that conflicts with m, if
+// m is part of it.
+func conflictingModifier(pair [2]Modifier, m Modifier) (Modifier, bool) {
+	switch m {
+	case pair[0]:
+		return pair[1], true
+	case pair[1]:
+		return pair[0], true
+	}
+	return "", false
+}
+
+// amendingMembers checks that the members of an amending module only amend the
+// members of its parent: they can not declare classes, type aliases, nor
+// properties with types or methods unless local.
+func (v *validator) amendingMembers(path string, members ModuleMembers) {
+	for i, member := range members {
+		memberPath := index(path, i)
+		switch member := member.(type) {
+		case *ModuleMemberGroup:
+			if member != nil {
+				v.amendingMembers(field(memberPath, "Members"), member.Members)
+			}
+		case *Class:
+			if member != nil {
+				v.errorf(memberPath, "classes can not be declared in amending modules")
+			}
+		case *TypeAlias:
+			if member != nil {
+				v.errorf(memberPath, "type aliases can not be declared in amending modules")
+			}
+		case *ClassProperty:
+			if member != nil && !isNil(member.Type) && !slices.Contains(member.Modifiers, ModifierLocal) {
+				v.errorf(field(memberPath, "Type"), "can not be set in amending modules unless the property is local")
+			}
+		case *ClassMethod:
+			if member != nil && member.Signature != nil && !slices.Contains(member.Signature.Modifiers, ModifierLocal) {
+				v.errorf(memberPath, "methods can not be declared in amending modules unless local")
+			}
+		}
+	}
+}
+
+// notWithBody checks a field that can not be set together with Body.
+func (v *validator) notWithBody(path string, n Node) {
+	if !isNil(n) {
+		v.errorf(path, "can not be set together with Body")
+	}
+}
+
+// valueOrBody checks the Value and Body fields of a member that must have
+// exactly one of them.
+func (v *validator) valueOrBody(path string, value Expression, body []*ObjectBody) {
+	switch {
+	case isNil(value) && body == nil:
+		v.errorf(field(path, "Value"), "is required if Body is not set")
+	case body != nil:
+		v.notWithBody(field(path, "Value"), value)
+	}
+	v.optional(field(path, "Value"), value)
+	nodes(v, field(path, "Body"), body)
+}
+
+func (v *validator) pounds(path string, pounds int) {
+	if pounds < 0 {
+		v.errorf(field(path, "Pounds"), "can not be negative")
+	}
+}
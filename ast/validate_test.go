@@ -0,0 +1,285 @@
+package ast
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		err  error
+	}{
+		{
+			name: "valid",
+			node: &Module{
+				Name:    "foo",
+				Imports: ImportClauses{&ImportClause{Path: "bar.pkl", Alias: "bar"}},
+				Members: ModuleMembers{
+					LineComment("Standalone."),
+					&ClassProperty{Name: Identifier("port"), Type: &DeclaredType{Name: "Int"}},
+					&ClassProperty{
+						Name: Identifier("server"),
+						Body: &ObjectBody{
+							Members: ObjectMembers{
+								&ObjectProperty{Name: Identifier("host"), Value: StringExpression("localhost")},
+								&ObjectEntry{Key: StringExpression("a"), Body: []*ObjectBody{{}}},
+								&ObjectElement{Value: &FunctionLiteralExpression{Body: IntExpression(1)}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "amends without name",
+			node: &Module{
+				ParentRelationship: ModuleRelationshipAmends,
+				ParentName:         "base.pkl",
+			},
+		},
+		{
+			name: "nil",
+			node: nil,
+			err:  ValidationErrors{{Path: "", Msg: "is required"}},
+		},
+		{
+			name: "nested path",
+			node: &Module{
+				Name: "foo",
+				Members: ModuleMembers{
+					&ClassProperty{Name: Identifier("a"), Expression: IntExpression(1)},
+					&ClassProperty{Name: Identifier("b"), Expression: IntExpression(2)},
+					&ClassProperty{
+						Name: Identifier("c"),
+						Body: &ObjectBody{
+							Members: ObjectMembers{
+								&ObjectElement{Value: IntExpression(0)},
+								&ObjectElement{Value: IntExpression(1)},
+								&ObjectElement{Value: IntExpression(2)},
+								&ObjectElement{Value: IntExpression(3)},
+								&ObjectElement{Value: IntExpression(4)},
+								&ObjectElement{},
+							},
+						},
+					},
+				},
+			},
+			err: ValidationErrors{{Path: "Members[2].Body.Members[5].Value", Msg: "is required"}},
+		},
+		{
+			name: "all violations",
+			node: &Module{
+				Imports: ImportClauses{&ImportClause{}, nil},
+				Members: ModuleMembers{
+					&ClassProperty{
+						Expression: StringExpression("bar"),
+						Body:       &ObjectBody{},
+					},
+					&ClassProperty{Name: Identifier("baz")},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Name", Msg: "is required if ParentRelationship is not set"},
+				{Path: "Imports[0].Path", Msg: "is required"},
+				{Path: "Imports[1]", Msg: "is required"},
+				{Path: "Members[0].Name", Msg: "is required"},
+				{Path: "Members[0].Expression", Msg: "can not be set together with Body"},
+				{Path: "Members[1].Expression", Msg: "is required if Type and Body are not set"},
+			},
+		},
+		{
+			name: "parent name without relationship",
+			node: &Module{Name: "foo", ParentName: "base.pkl"},
+			err:  ValidationErrors{{Path: "ParentRelationship", Msg: "is required if ParentName is set"}},
+		},
+		{
+			name: "value and body",
+			node: &ObjectBody{
+				Members: ObjectMembers{
+					&ObjectProperty{Name: Identifier("a"), Value: IntExpression(1), Body: []*ObjectBody{{}}},
+					&ObjectEntry{Key: IntExpression(1), Value: IntExpression(1), Body: []*ObjectBody{nil}},
+					&MemberPredicate{Condition: ExpressionTrue},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Members[0].Value", Msg: "can not be set together with Body"},
+				{Path: "Members[1].Value", Msg: "can not be set together with Body"},
+				{Path: "Members[1].Body[0]", Msg: "is required"},
+				{Path: "Members[2].Value", Msg: "is required if Body is not set"},
+			},
+		},
+		{
+			name: "nil pointer",
+			node: &BinaryExpression{
+				Operator: BinaryOperatorPlus,
+				Left:     (*MemberAccessExpression)(nil),
+				Right:    IntExpression(1),
+			},
+			err: ValidationErrors{{Path: "Left", Msg: "is required"}},
+		},
+		{
+			name: "invalid values",
+			node: &ReadExpression{
+				Variant: "maybe",
+				Value: &BinaryExpression{
+					Operator: "^",
+					Left:     &MemberAccessExpression{Name: "a`b"},
+					Right:    &FormattedIntExpression{Value: 1, Radix: 3},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Variant", Msg: `invalid value "maybe"`},
+				{Path: "Value.Operator", Msg: `invalid value "^"`},
+				{Path: "Value.Left.Name", Msg: "identifier \"a`b\" has backticks or line breaks"},
+				{Path: "Value.Right.Radix", Msg: "invalid radix 3"},
+			},
+		},
+		{
+			name: "interpolation",
+			node: &InterpolatedStringExpression{
+				Parts: StringParts{
+					StringText("a"),
+					&StringInterpolation{},
+					nil,
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Parts[1].Expression", Msg: "is required"},
+				{Path: "Parts[2]", Msg: "is required"},
+			},
+		},
+		{
+			name: "modifiers",
+			node: &Module{
+				Name:      "foo",
+				Modifiers: Modifiers{ModifierLocal},
+				Members: ModuleMembers{
+					&Class{Name: Identifier("Bar"), Modifiers: Modifiers{ModifierOpen, ModifierOpen}},
+					&ClassMethod{
+						Signature: &MethodSignature{Name: Identifier("baz"), Modifiers: Modifiers{ModifierHidden}},
+					},
+					&ClassProperty{
+						Name: Identifier("qux"),
+						Body: &ObjectBody{
+							Members: ObjectMembers{
+								&ObjectProperty{Name: Identifier("a"), Modifiers: Modifiers{ModifierFixed}, Value: IntExpression(1)},
+							},
+						},
+					},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Modifiers[0]", Msg: `"local" is not a valid module modifier`},
+				{Path: "Members[0].Modifiers[1]", Msg: `duplicated modifier "open"`},
+				{Path: "Members[1].Signature.Modifiers[0]", Msg: `"hidden" is not a valid method modifier`},
+				{Path: "Members[2].Body.Members[0].Modifiers[0]", Msg: `"fixed" is not a valid object member modifier`},
+			},
+		},
+		{
+			name: "amending module",
+			node: &Module{
+				Modifiers:          Modifiers{ModifierOpen},
+				ParentRelationship: ModuleRelationshipAmends,
+				ParentName:         "base.pkl",
+				Members: ModuleMembers{
+					&ClassProperty{Name: Identifier("a"), Expression: IntExpression(1)},
+					&ClassProperty{Name: Identifier("b"), Type: &DeclaredType{Name: "Int"}},
+					&ClassProperty{
+						Modifiers: Modifiers{ModifierLocal},
+						Name:      Identifier("c"),
+						Type:      &DeclaredType{Name: "Int"},
+					},
+					&ModuleMemberGroup{
+						Members: ModuleMembers{
+							&ClassMethod{Signature: &MethodSignature{Name: Identifier("d")}},
+							&ClassMethod{
+								Signature:      &MethodSignature{Modifiers: Modifiers{ModifierLocal}, Name: Identifier("e")},
+								Implementation: IntExpression(1),
+							},
+						},
+					},
+					&TypeAlias{Name: Identifier("F"), Type: &DeclaredType{Name: "Int"}},
+					&Class{Name: Identifier("G")},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Modifiers[0]", Msg: `"open" is not a valid amending module modifier`},
+				{Path: "Members[1].Type", Msg: "can not be set in amending modules unless the property is local"},
+				{Path: "Members[3].Members[0]", Msg: "methods can not be declared in amending modules unless local"},
+				{Path: "Members[4]", Msg: "type aliases can not be declared in amending modules"},
+				{Path: "Members[5]", Msg: "classes can not be declared in amending modules"},
+			},
+		},
+		{
+			name: "extends without parent name",
+			node: &Module{Name: "foo", ParentRelationship: ModuleRelationshipExtends},
+			err:  ValidationErrors{{Path: "ParentName", Msg: "is required if ParentRelationship is set"}},
+		},
+		{
+			name: "types",
+			node: &TypeAlias{
+				Name: Identifier("Foo"),
+				Type: &UnionType{
+					Members: []Type{
+						&NullableType{},
+						&FunctionLiteralType{Parameters: []Type{&DeclaredType{}}},
+					},
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Type.Members[0].Type", Msg: "is required"},
+				{Path: "Type.Members[1].Parameters[0].Name", Msg: "is required"},
+				{Path: "Type.Members[1].Result", Msg: "is required"},
+			},
+		},
+		{
+			name: "union members after default",
+			node: &TypeAlias{
+				Name: Identifier("Foo"),
+				Type: &UnionType{
+					Members:             []Type{StringLiteralType("a")},
+					MembersAfterDefault: 1,
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Type.MembersAfterDefault", Msg: "can not be set if Default is not set"},
+			},
+		},
+		{
+			name: "union too many members after default",
+			node: &TypeAlias{
+				Name: Identifier("Foo"),
+				Type: &UnionType{
+					Members:             []Type{StringLiteralType("a")},
+					Default:             StringLiteralType("b"),
+					MembersAfterDefault: 2,
+				},
+			},
+			err: ValidationErrors{
+				{Path: "Type.MembersAfterDefault", Msg: "can not be greater than the number of members"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.node)
+
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	err := (&Module{Members: ModuleMembers{&ClassMethod{}}}).Validate()
+
+	assert.EqualError(t, err, "Name: is required if ParentRelationship is not set\nMembers[0].Signature: is required")
+
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "Name", validationErr.Path)
+}
@@ -0,0 +1,153 @@
+// Package cst implements a lossless concrete syntax tree for Pkl source
+// code. Unlike the nodes of package ast, it keeps every token, blank line and
+// comment of the source, so an unmodified tree prints back exactly the bytes
+// it was parsed from.
+//
+// Each Node of the tree wraps the ast node it was parsed into, and groups the
+// tokens the ast node spans. FromAST builds the tree of an ast node, and
+// ToAST converts a tree, modified or not, back into an ast node.
+package cst
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/token"
+)
+
+// TriviaKind is the kind of a Trivia.
+type TriviaKind int
+
+const (
+	// Spaces and tabs.
+	Whitespace TriviaKind = iota
+	// A line break, either "\n" or "\r\n".
+	Newline
+	LineComment
+	BlockComment
+)
+
+// Trivia is source code that does not affect the meaning of a module:
+// whitespace, line breaks and comments.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Token is a token of the source code, along with the trivia around it.
+type Token struct {
+	Kind token.Kind
+	Text string
+	// Trivia between the previous token's trailing trivia and this token.
+	Leading []Trivia
+	// Trivia after this token, up to the end of its line.
+	Trailing []Trivia
+
+	// Offset of Text in the source code it was parsed from.
+	offset int
+}
+
+// Element is either a *Node or a *Token.
+type Element interface {
+	io.WriterTo
+	element()
+}
+
+// Node is a node of the concrete syntax tree.
+type Node struct {
+	// The ast node the tokens were parsed into. Nodes of the tree that were
+	// modified may not match it anymore: ToAST parses them again.
+	AST ast.Node
+	// Tokens and nested nodes, in source order.
+	Children []Element
+}
+
+func (*Token) element() {}
+func (*Node) element()  {}
+
+// WriteTo writes the token and its trivia to w.
+func (t *Token) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, trivia := range t.Leading {
+		b.WriteString(trivia.Text)
+	}
+	b.WriteString(t.Text)
+	for _, trivia := range t.Trailing {
+		b.WriteString(trivia.Text)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// WriteTo writes the source code of the node to w.
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, child := range n.Children {
+		written, err := child.WriteTo(w)
+		total += written
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Bytes returns the source code of the node.
+func (n *Node) Bytes() []byte {
+	var b bytes.Buffer
+	_, _ = n.WriteTo(&b)
+	return b.Bytes()
+}
+
+func (n *Node) String() string {
+	return string(n.Bytes())
+}
+
+// Tokens returns the tokens of the node, in source order.
+func (n *Node) Tokens() []*Token {
+	var toks []*Token
+	n.Inspect(func(child Element) bool {
+		if tok, ok := child.(*Token); ok {
+			toks = append(toks, tok)
+		}
+		return true
+	})
+	return toks
+}
+
+// Inspect traverses the node in depth-first order, calling f for every
+// nested element. If f returns false, the children of that element are not
+// traversed.
+func (n *Node) Inspect(f func(Element) bool) {
+	for _, child := range n.Children {
+		if !f(child) {
+			continue
+		}
+		if node, ok := child.(*Node); ok {
+			node.Inspect(f)
+		}
+	}
+}
+
+// Find returns the node of the tree that wraps the given ast node, or nil if
+// there is none.
+func (n *Node) Find(target ast.Node) *Node {
+	if n.AST == target {
+		return n
+	}
+
+	var found *Node
+	n.Inspect(func(child Element) bool {
+		if found != nil {
+			return false
+		}
+		if node, ok := child.(*Node); ok && node.AST == target {
+			found = node
+		}
+		return found == nil
+	})
+	return found
+}
@@ -0,0 +1,53 @@
+package cst
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/token"
+)
+
+func TestNodeWriteTo(t *testing.T) {
+	n := &Node{
+		Children: []Element{
+			&Token{Kind: token.IDENT, Text: "foo", Trailing: []Trivia{{Kind: Whitespace, Text: " "}}},
+			&Token{Kind: token.ASSIGN, Text: "=", Trailing: []Trivia{{Kind: Whitespace, Text: " "}}},
+			&Node{
+				Children: []Element{
+					&Token{Kind: token.INT, Text: "1"},
+				},
+			},
+			&Token{
+				Kind:    token.EOF,
+				Leading: []Trivia{{Kind: Newline, Text: "\n"}, {Kind: LineComment, Text: "// End."}},
+			},
+		},
+	}
+
+	assert.Equal(t, "foo = 1\n// End.", n.String())
+	assert.Len(t, n.Tokens(), 4)
+
+	_, err := n.WriteTo(failingWriter{})
+	assert.EqualError(t, err, "write failed")
+}
+
+func TestNodeInspect(t *testing.T) {
+	inner := &Node{Children: []Element{&Token{Kind: token.INT, Text: "1"}}}
+	n := &Node{Children: []Element{&Token{Kind: token.IDENT, Text: "foo"}, inner}}
+
+	var visited []Element
+	n.Inspect(func(e Element) bool {
+		visited = append(visited, e)
+		return e != inner
+	})
+
+	assert.Equal(t, []Element{n.Children[0], inner}, visited)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
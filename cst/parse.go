@@ -0,0 +1,332 @@
+package cst
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/parser"
+	"github.com/pauloborges/balsamic/scanner"
+	"github.com/pauloborges/balsamic/token"
+)
+
+// Parse parses the source code of a Pkl module read from filename. The
+// returned node wraps the *ast.Module, and its last token is the end of
+// file, which holds the trivia at the end of the source code.
+func Parse(filename string, src []byte) (*Node, error) {
+	m, err := parser.ParseFile(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	toks := tokenize(src)
+	return build(m, toks, 0, len(toks)), nil
+}
+
+// FromAST builds the tree of an ast node by printing it and parsing the
+// result. The node must be a module, a module, class or object member, an
+// object body, an annotation, an expression or a type.
+func FromAST(n ast.Node) (*Node, error) {
+	prefix, suffix, ok := fragment(n)
+	if !ok {
+		return nil, fmt.Errorf("cst: unsupported node %T", n)
+	}
+
+	b, err := n.Marshal(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := n.(*ast.Module); ok {
+		return Parse("", b)
+	}
+
+	src := make([]byte, 0, len(prefix)+len(b)+len(suffix))
+	src = append(src, prefix...)
+	src = append(src, b...)
+	src = append(src, suffix...)
+
+	m, err := parser.ParseFile("", src)
+	if err != nil {
+		return nil, fmt.Errorf("cst: %T does not print valid Pkl: %w", n, err)
+	}
+
+	toks := tokenize(src)
+	lo := sort.Search(len(toks), func(i int) bool { return toks[i].offset >= len(prefix) })
+	hi := sort.Search(len(toks), func(i int) bool { return toks[i].offset >= len(prefix)+len(b) })
+	if lo == hi {
+		return nil, fmt.Errorf("cst: %T printed no tokens", n)
+	}
+
+	// Use the parsed node, which has the spans needed to nest the tokens.
+	// Scalar nodes have no span and no nested nodes, so they are kept.
+	parsed := findParsed(m, reflect.TypeOf(n), len(prefix))
+	if parsed == nil {
+		parsed = n
+	}
+
+	// The trivia around the fragment belongs to the scaffolding.
+	toks[lo].Leading = nil
+	toks[hi-1].Trailing = nil
+
+	return build(parsed, toks, lo, hi), nil
+}
+
+// ToAST parses the source code of a tree into the kind of ast node it wraps,
+// which must be one of the kinds FromAST supports. Unlike the AST field of
+// the tree, which is the node the tree was built from, the result reflects
+// the changes made to the tokens of the tree since, and has its comments
+// attached as with parser.ParseComments. Only the positions of a module
+// match the source code of the tree, as the other nodes are parsed
+// surrounded by the source code they need to parse as a module.
+func ToAST(n *Node) (ast.Node, error) {
+	prefix, suffix, ok := fragment(n.AST)
+	if !ok {
+		return nil, fmt.Errorf("cst: unsupported node %T", n.AST)
+	}
+
+	if _, ok := n.AST.(*ast.Module); ok {
+		return parser.ParseFileMode("", n.Bytes(), parser.ParseComments)
+	}
+
+	toks := n.Tokens()
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("cst: %T has no tokens", n.AST)
+	}
+
+	b := n.Bytes()
+	src := make([]byte, 0, len(prefix)+len(b)+len(suffix))
+	src = append(src, prefix...)
+	src = append(src, b...)
+	src = append(src, suffix...)
+
+	m, err := parser.ParseFileMode("", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("cst: %T does not parse: %w", n.AST, err)
+	}
+
+	// The node starts at its first token, after its leading trivia.
+	start := len(prefix)
+	for _, trivia := range toks[0].Leading {
+		start += len(trivia.Text)
+	}
+	if parsed := findParsed(m, reflect.TypeOf(n.AST), start); parsed != nil {
+		return parsed, nil
+	}
+
+	// Scalar nodes have no span, so they are found by their place in the
+	// source code.
+	if len(m.Members) == 1 {
+		if p, ok := m.Members[0].(*ast.ClassProperty); ok {
+			switch n.AST.(type) {
+			case ast.Expression:
+				return p.Expression, nil
+			case ast.Type:
+				return p.Type, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cst: %T does not parse as %T", n.AST, n.AST)
+}
+
+// fragment returns the source code that must surround the printed node for
+// it to parse as a module.
+func fragment(n ast.Node) (prefix, suffix string, ok bool) {
+	switch n.(type) {
+	case *ast.Module:
+		return "", "", true
+	case ast.ModuleMember, ast.ClassMember:
+		return "", "", true
+	case ast.ObjectMember:
+		return "x {\n", "\n}", true
+	case *ast.ObjectBody:
+		return "x ", "", true
+	case *ast.Annotation:
+		return "", "\nx = 0", true
+	case ast.Expression:
+		return "x = ", "", true
+	case ast.Type:
+		return "x: ", "", true
+	}
+	return "", "", false
+}
+
+// findParsed returns the outermost node of type typ whose span starts at
+// offset.
+func findParsed(m *ast.Module, typ reflect.Type, offset int) ast.Node {
+	var found ast.Node
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		if found != nil {
+			return
+		}
+		if reflect.TypeOf(n) == typ && ast.Position(n).Start.Offset == offset {
+			found = n
+			return
+		}
+		for _, child := range children(n) {
+			visit(child)
+		}
+	}
+	visit(m)
+	return found
+}
+
+// tokenize returns the tokens of src, ending with EOF, with comments and
+// whitespace attached as trivia.
+func tokenize(src []byte) []*Token {
+	var toks []*Token
+	var pending []Trivia
+	// Whether the trivia found goes to the last token, until the end of its
+	// line.
+	trailing := false
+	end := 0
+
+	addTrivia := func(trivia Trivia) {
+		if trivia.Kind == Newline {
+			trailing = false
+		}
+		if trailing {
+			last := toks[len(toks)-1]
+			last.Trailing = append(last.Trailing, trivia)
+		} else {
+			pending = append(pending, trivia)
+		}
+	}
+
+	addWhitespace := func(s string) {
+		for len(s) > 0 {
+			i := 0
+			switch {
+			case s[0] == '\n':
+				addTrivia(Trivia{Kind: Newline, Text: s[:1]})
+				s = s[1:]
+				continue
+			case len(s) > 1 && s[0] == '\r' && s[1] == '\n':
+				addTrivia(Trivia{Kind: Newline, Text: s[:2]})
+				s = s[2:]
+				continue
+			}
+			for i < len(s) && s[i] != '\n' && !(s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n') {
+				i++
+			}
+			addTrivia(Trivia{Kind: Whitespace, Text: s[:i]})
+			s = s[i:]
+		}
+	}
+
+	for _, tok := range scanner.Tokenize(src, nil, scanner.ScanComments) {
+		addWhitespace(string(src[end:tok.Pos.Offset]))
+		end = tok.End.Offset
+
+		switch tok.Kind {
+		case token.LINE_COMMENT:
+			addTrivia(Trivia{Kind: LineComment, Text: tok.Text})
+		case token.BLOCK_COMMENT:
+			addTrivia(Trivia{Kind: BlockComment, Text: tok.Text})
+		default:
+			toks = append(toks, &Token{
+				Kind:    tok.Kind,
+				Text:    tok.Text,
+				Leading: pending,
+				offset:  tok.Pos.Offset,
+			})
+			pending = nil
+			trailing = tok.Kind != token.EOF
+		}
+	}
+
+	return toks
+}
+
+// build returns the node of n, made of the tokens from lo to hi. The tokens
+// spanned by the nested ast nodes are grouped in nested nodes.
+func build(n ast.Node, toks []*Token, lo, hi int) *Node {
+	node := &Node{AST: n}
+
+	nested := children(n)
+	sort.SliceStable(nested, func(i, j int) bool {
+		return ast.Position(nested[i]).Start.Offset < ast.Position(nested[j]).Start.Offset
+	})
+
+	cur := lo
+	for _, child := range nested {
+		span := ast.Position(child)
+
+		start := cur
+		for start < hi && toks[start].offset < span.Start.Offset {
+			start++
+		}
+		end := start
+		for end < hi && toks[end].offset < span.End.Offset {
+			end++
+		}
+		// Nodes overlapping the previous one are already covered by it.
+		if start == end || toks[start].offset < span.Start.Offset {
+			continue
+		}
+
+		for _, tok := range toks[cur:start] {
+			node.Children = append(node.Children, tok)
+		}
+		node.Children = append(node.Children, build(child, toks, start, end))
+		cur = end
+	}
+
+	for _, tok := range toks[cur:hi] {
+		node.Children = append(node.Children, tok)
+	}
+
+	return node
+}
+
+// children returns the outermost ast nodes with a known span nested in n.
+func children(n ast.Node) []ast.Node {
+	var nodes []ast.Node
+	v := reflect.ValueOf(n)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		for i := range v.NumField() {
+			collect(v.Field(i), &nodes)
+		}
+	}
+	return nodes
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+func collect(v reflect.Value, nodes *[]ast.Node) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+	}
+
+	if v.CanInterface() && v.Type().Implements(nodeType) {
+		if n := v.Interface().(ast.Node); ast.Position(n).IsValid() {
+			*nodes = append(*nodes, n)
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		collect(v.Elem(), nodes)
+	case reflect.Slice:
+		for i := range v.Len() {
+			collect(v.Index(i), nodes)
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(ast.Span{}) {
+			return
+		}
+		for i := range v.NumField() {
+			collect(v.Field(i), nodes)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package cst
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/pauloborges/balsamic/token"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{
+			name: "empty",
+			src:  "",
+		},
+		{
+			name: "comments and blank lines",
+			src: stringsutil.StripMargin(`
+				|#!/usr/bin/env pkl
+				|// License header.
+				|
+				|module foo // The module.
+				|
+				|import "bar.pkl"
+				|
+				|
+				|/// Docs.
+				|class Foo {
+				|  /* Block
+				|     comment. */
+				|  bar: Int   =   1 // Odd spacing.
+				|}
+				|
+			`),
+		},
+		{
+			name: "objects and strings",
+			src: stringsutil.StripMargin(`
+				|foo {
+				|  ["a"] = #"b"#; c = """
+				|    multi
+				|    """
+				|  for (k, v in bar) { [k] = v }
+				|}
+			`),
+		},
+		{
+			name: "crlf and trailing whitespace",
+			src:  "foo = 1 \r\n\r\nbar = 2\t\r\n  ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := Parse("", []byte(test.src))
+			assert.NoError(t, err)
+			assert.Equal(t, test.src, n.String())
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("broken.pkl", []byte("foo = ("))
+	assert.EqualError(t, err, "broken.pkl:1:8: expected expression, found end of file")
+}
+
+func TestParseTrivia(t *testing.T) {
+	src := stringsutil.StripMargin(`
+		|// Leading.
+		|foo = 1 // Trailing.
+		|
+		|bar = 2
+	`)
+
+	n, err := Parse("", []byte(src))
+	assert.NoError(t, err)
+
+	toks := n.Tokens()
+	assert.Equal(t, "foo", toks[0].Text)
+	assert.Equal(t, []Trivia{
+		{Kind: LineComment, Text: "// Leading."},
+		{Kind: Newline, Text: "\n"},
+	}, toks[0].Leading)
+
+	assert.Equal(t, "1", toks[2].Text)
+	assert.Equal(t, []Trivia{
+		{Kind: Whitespace, Text: " "},
+		{Kind: LineComment, Text: "// Trailing."},
+	}, toks[2].Trailing)
+
+	assert.Equal(t, "bar", toks[3].Text)
+	assert.Equal(t, []Trivia{
+		{Kind: Newline, Text: "\n"},
+		{Kind: Newline, Text: "\n"},
+	}, toks[3].Leading)
+}
+
+func TestParseNesting(t *testing.T) {
+	src := stringsutil.StripMargin(`
+		|module foo
+		|
+		|bar {
+		|  baz = 1 + 2 // Sum.
+		|}
+	`)
+
+	n, err := Parse("", []byte(src))
+	assert.NoError(t, err)
+
+	m := n.AST.(*ast.Module)
+	bar := m.Members[0].(*ast.ClassProperty)
+	baz := bar.Body.Members[0].(*ast.ObjectProperty)
+
+	assert.Equal(t, "\n\nbar {\n  baz = 1 + 2 // Sum.\n}", n.Find(bar).String())
+	assert.Equal(t, "\n  baz = 1 + 2 // Sum.", n.Find(baz).String())
+	assert.Equal(t, "1 + 2 // Sum.", n.Find(baz.Value).String())
+	assert.Nil(t, n.Find(&ast.ObjectProperty{}))
+}
+
+func TestFromAST(t *testing.T) {
+	tests := []struct {
+		name string
+		node ast.Node
+		res  string
+	}{
+		{
+			name: "module",
+			node: &ast.Module{
+				Name:    "foo",
+				Members: ast.ModuleMembers{&ast.ClassProperty{Name: "bar", Expression: ast.IntExpression(1)}},
+			},
+			res: "module foo\n\nbar = 1\n",
+		},
+		{
+			name: "class member",
+			node: &ast.ClassProperty{Name: "bar", Type: &ast.DeclaredType{Name: "Int"}},
+			res:  "bar: Int",
+		},
+		{
+			name: "object member",
+			node: &ast.ObjectEntry{Key: ast.StringExpression("a"), Value: ast.IntExpression(1)},
+			res:  `["a"] = 1`,
+		},
+		{
+			name: "expression",
+			node: &ast.BinaryExpression{
+				Left:     ast.IntExpression(1),
+				Operator: ast.BinaryOperatorPlus,
+				Right:    ast.IntExpression(2),
+			},
+			res: "1 + 2",
+		},
+		{
+			name: "scalar expression",
+			node: ast.IntExpression(42),
+			res:  "42",
+		},
+		{
+			name: "type",
+			node: &ast.NullableType{Type: &ast.DeclaredType{Name: "String"}},
+			res:  "String?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := FromAST(test.node)
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, n.String())
+			assert.IsType(t, test.node, n.AST)
+		})
+	}
+}
+
+func TestFromASTUnsupported(t *testing.T) {
+	_, err := FromAST(ast.Modifiers{ast.ModifierLocal})
+	assert.EqualError(t, err, "cst: unsupported node ast.Modifiers")
+}
+
+func TestToAST(t *testing.T) {
+	src := stringsutil.StripMargin(`
+		|module foo
+		|
+		|bar {
+		|  // The sum.
+		|  baz = 1 + 2 // Sum.
+		|}
+	`)
+
+	n, err := Parse("", []byte(src))
+	assert.NoError(t, err)
+
+	m := n.AST.(*ast.Module)
+	bar := m.Members[0].(*ast.ClassProperty)
+	baz := bar.Body.Members[0].(*ast.ObjectProperty)
+
+	// Change 2 into 3.
+	toks := n.Find(baz.Value).Tokens()
+	toks[len(toks)-1].Text = "3"
+
+	tests := []struct {
+		name string
+		node *Node
+		res  string
+	}{
+		{
+			name: "module",
+			node: n,
+			res:  "module foo\n\nbar {\n  // The sum.\n  baz = 1 + 3 // Sum.\n}\n",
+		},
+		{
+			name: "object member",
+			node: n.Find(baz),
+			res:  "// The sum.\nbaz = 1 + 3 // Sum.",
+		},
+		{
+			name: "expression",
+			node: n.Find(baz.Value),
+			res:  "1 + 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := ToAST(test.node)
+			assert.NoError(t, err)
+			assert.IsType(t, test.node.AST, res)
+
+			b, err := res.Marshal(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(b))
+		})
+	}
+
+	// The tree keeps the ast node it was built from.
+	assert.Equal(t, ast.IntExpression(2), n.Find(baz.Value).AST.(*ast.BinaryExpression).Right)
+}
+
+func TestToASTFromAST(t *testing.T) {
+	tests := []ast.Node{
+		&ast.ClassProperty{Name: "bar", Type: &ast.DeclaredType{Name: "Int"}},
+		&ast.ObjectEntry{Key: ast.StringExpression("a"), Value: ast.IntExpression(1)},
+		ast.IntExpression(42),
+		&ast.NullableType{Type: &ast.DeclaredType{Name: "String"}},
+	}
+
+	for _, node := range tests {
+		n, err := FromAST(node)
+		assert.NoError(t, err)
+
+		res, err := ToAST(n)
+		assert.NoError(t, err)
+
+		expected, _ := node.Marshal(context.Background())
+		actual, _ := res.Marshal(context.Background())
+		assert.Equal(t, string(expected), string(actual))
+	}
+}
+
+func TestToASTErrors(t *testing.T) {
+	_, err := ToAST(&Node{AST: ast.Modifiers{ast.ModifierLocal}})
+	assert.EqualError(t, err, "cst: unsupported node ast.Modifiers")
+
+	_, err = ToAST(&Node{AST: ast.IntExpression(1), Children: []Element{&Token{Kind: token.IDENT, Text: "{"}}})
+	assert.EqualError(t, err, "cst: ast.IntExpression does not parse: 1:5: expected expression, found '{'")
+}
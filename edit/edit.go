@@ -0,0 +1,551 @@
+// Package edit changes Pkl source code with text edits limited to the nodes
+// changed, keeping the formatting and comments of the rest of the source
+// untouched.
+package edit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/parser"
+	"github.com/pauloborges/balsamic/scanner"
+	"github.com/pauloborges/balsamic/token"
+)
+
+// Edit replaces the source code between the byte offsets Start and End with
+// Text. Edits with Start equal to End are insertions.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Apply applies the edits to src. The edits are expressed against src, so
+// they must not overlap. Insertions at the same offset are applied in order.
+func Apply(src []byte, edits []Edit) ([]byte, error) {
+	edits = sorted(edits)
+
+	var b bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		if edit.Start < last || edit.End < edit.Start || edit.End > len(src) {
+			return nil, fmt.Errorf("edit: invalid or overlapping edit of %d:%d", edit.Start, edit.End)
+		}
+		b.Write(src[last:edit.Start])
+		b.WriteString(edit.Text)
+		last = edit.End
+	}
+	b.Write(src[last:])
+
+	return b.Bytes(), nil
+}
+
+func sorted(edits []Edit) []Edit {
+	edits = append([]Edit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	return edits
+}
+
+// ErrNoPosition is returned when editing a node that was not parsed from the
+// source code of the Editor.
+var ErrNoPosition = errors.New("edit: node has no position in the source code")
+
+// Editor computes the edits that change a parsed module.
+//
+// Nodes are edited through the module returned by Module, which is never
+// modified: every edit is expressed against the original source code, so
+// indexes and nodes always refer to the module as parsed.
+type Editor struct {
+	src  []byte
+	mod  *ast.Module
+	toks []token.Token
+	// The line ending of the source code, used in the lines edits add.
+	newline string
+	edits   []Edit
+}
+
+// NewEditor parses the source code of a Pkl module read from filename.
+func NewEditor(filename string, src []byte) (*Editor, error) {
+	mod, err := parser.ParseFile(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Editor{
+		src:     src,
+		mod:     mod,
+		toks:    scanner.Tokenize(src, nil, scanner.ScanComments),
+		newline: lineEnding(src),
+	}, nil
+}
+
+// lineEnding returns the line ending of src: "\r\n" if its first line ends
+// with it, or "\n".
+func lineEnding(src []byte) string {
+	if i := bytes.IndexByte(src, '\n'); i > 0 && src[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// Module returns the parsed module.
+func (e *Editor) Module() *ast.Module {
+	return e.mod
+}
+
+// Edits returns the edits made so far, sorted by offset.
+func (e *Editor) Edits() []Edit {
+	return sorted(e.edits)
+}
+
+// Bytes returns the source code with the edits applied.
+func (e *Editor) Bytes() ([]byte, error) {
+	return Apply(e.src, e.edits)
+}
+
+// Replace replaces the source code of old with new.
+func (e *Editor) Replace(old, new ast.Node) error {
+	span, err := e.span(old)
+	if err != nil {
+		return err
+	}
+
+	text, err := e.print(new, span.Start.Offset)
+	if err != nil {
+		return err
+	}
+
+	e.add(span.Start.Offset, span.End.Offset, text)
+	return nil
+}
+
+// SetExpression sets the value of a property or entry, replacing its current
+// value, type-only declaration or object bodies. The node must be a
+// *ast.ClassProperty, *ast.ObjectProperty or *ast.ObjectEntry.
+func (e *Editor) SetExpression(n ast.Node, expr ast.Expression) error {
+	var value ast.Expression
+	var bodies []*ast.ObjectBody
+
+	switch n := n.(type) {
+	case *ast.ClassProperty:
+		value = n.Expression
+		if n.Body != nil {
+			bodies = []*ast.ObjectBody{n.Body}
+		}
+	case *ast.ObjectProperty:
+		value, bodies = n.Value, n.Body
+	case *ast.ObjectEntry:
+		value, bodies = n.Value, n.Body
+	default:
+		return fmt.Errorf("edit: can not set the expression of %T", n)
+	}
+
+	span, err := e.span(n)
+	if err != nil {
+		return err
+	}
+
+	text, err := e.print(expr, span.Start.Offset)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case value != nil:
+		// The value is whatever follows the first '=' outside brackets,
+		// which works for values without a position of their own.
+		assign := e.find(span, token.ASSIGN)
+		if assign < 0 {
+			return ErrNoPosition
+		}
+		next := assign + 1
+		for e.toks[next].Kind.IsComment() {
+			next++
+		}
+		e.add(e.toks[next].Pos.Offset, span.End.Offset, text)
+	case len(bodies) > 0:
+		start := ast.Position(bodies[0]).Start.Offset
+		e.add(start, span.End.Offset, "= "+text)
+	default:
+		e.add(span.End.Offset, span.End.Offset, " = "+text)
+	}
+
+	return nil
+}
+
+// Insert inserts n as the member at index of parent, shifting the members
+// from index on. Supported are imports and members of an *ast.Module, members
+// of an *ast.Class and members of an *ast.ObjectBody.
+func (e *Editor) Insert(parent ast.Node, index int, n ast.Node) error {
+	var siblings []ast.Node
+	// Whether members are separated by blank lines.
+	blank := false
+
+	switch parent := parent.(type) {
+	case *ast.Module:
+		switch n := n.(type) {
+		case *ast.ImportClause:
+			siblings = nodes(parent.Imports)
+			if len(siblings) == 0 {
+				return e.insertFirstImport(n)
+			}
+		case ast.ModuleMember:
+			siblings = nodes(parent.Members)
+			blank = true
+			if len(siblings) == 0 {
+				return e.insertAtEnd(n)
+			}
+		default:
+			return fmt.Errorf("edit: can not insert %T in a module", n)
+		}
+	case *ast.Class:
+		member, ok := n.(ast.ClassMember)
+		if !ok {
+			return fmt.Errorf("edit: can not insert %T in a class", n)
+		}
+		siblings = nodes(parent.Members)
+		blank = true
+		if len(siblings) == 0 || e.inline(parent) {
+			if index < 0 || index > len(siblings) {
+				return fmt.Errorf("edit: index %d out of range", index)
+			}
+			class := *parent
+			class.Members = insert(parent.Members, index, member)
+			return e.Replace(parent, &class)
+		}
+	case *ast.ObjectBody:
+		member, ok := n.(ast.ObjectMember)
+		if !ok {
+			return fmt.Errorf("edit: can not insert %T in an object body", n)
+		}
+		siblings = nodes(parent.Members)
+		if e.inline(parent) {
+			if index < 0 || index > len(siblings) {
+				return fmt.Errorf("edit: index %d out of range", index)
+			}
+			body := *parent
+			body.Members = insert(parent.Members, index, member)
+			return e.Replace(parent, &body)
+		}
+		if len(siblings) == 0 {
+			return e.insertInEmptyBody(parent, n)
+		}
+	default:
+		return fmt.Errorf("edit: can not insert members in %T", parent)
+	}
+
+	if index < 0 || index > len(siblings) {
+		return fmt.Errorf("edit: index %d out of range", index)
+	}
+
+	sep := e.newline
+	if blank {
+		sep = e.newline + e.newline
+	}
+
+	if index < len(siblings) {
+		span, err := e.span(siblings[index])
+		if err != nil {
+			return err
+		}
+		start := span.Start.Offset
+		text, err := e.print(n, start)
+		if err != nil {
+			return err
+		}
+		if e.lineStartsAt(start) {
+			// The member goes on its own lines, before the comments of the
+			// sibling.
+			at := e.lineStart(e.commentsStart(start))
+			e.add(at, at, e.indentation(start)+text+sep)
+		} else {
+			e.add(start, start, text+sep+e.indentation(start))
+		}
+		return nil
+	}
+
+	span, err := e.span(siblings[len(siblings)-1])
+	if err != nil {
+		return err
+	}
+	text, err := e.print(n, span.Start.Offset)
+	if err != nil {
+		return err
+	}
+	at := e.endOfLine(span.End.Offset)
+	e.add(at, at, sep+e.indentation(span.Start.Offset)+text)
+	return nil
+}
+
+// Remove removes the source code of n, along with the lines it leaves empty
+// and the comments right above it.
+func (e *Editor) Remove(n ast.Node) error {
+	span, err := e.span(n)
+	if err != nil {
+		return err
+	}
+
+	start, end := span.Start.Offset, span.End.Offset
+	if !e.lineStartsAt(start) || e.endOfLine(end) == end && end != e.lineEnd(end) {
+		// The node shares its lines with other code: remove it and the
+		// whitespace after it.
+		for end < len(e.src) && (e.src[end] == ' ' || e.src[end] == '\t') {
+			end++
+		}
+		e.add(start, end, "")
+		return nil
+	}
+
+	start = e.lineStart(e.commentsStart(start))
+	end = e.nextLine(e.endOfLine(end))
+
+	// Do not leave two blank lines in a row, or a blank line at the end of
+	// a body or of the file.
+	if start > 0 && e.blankLine(e.lineStart(start-1)) &&
+		(end == len(e.src) || e.blankLine(end) || e.closesBody(end)) {
+		start = e.lineStart(start - 1)
+	}
+
+	e.add(start, end, "")
+	return nil
+}
+
+func (e *Editor) add(start, end int, text string) {
+	e.edits = append(e.edits, Edit{Start: start, End: end, Text: text})
+}
+
+func (e *Editor) span(n ast.Node) (ast.Span, error) {
+	span := ast.Position(n)
+	if !span.IsValid() || span.End.Offset > len(e.src) {
+		return span, ErrNoPosition
+	}
+	return span, nil
+}
+
+// print returns the source code of n, indented to be placed in the line of
+// offset, with the line ending of the source code.
+func (e *Editor) print(n ast.Node, offset int) (string, error) {
+	b, err := n.Marshal(context.Background())
+	if err != nil {
+		return "", err
+	}
+	text := indentLines(string(b), e.indentation(offset))
+	return strings.ReplaceAll(text, "\n", e.newline), nil
+}
+
+func (e *Editor) insertFirstImport(n *ast.ImportClause) error {
+	text, err := e.print(n, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(e.mod.Members) > 0 {
+		span, err := e.span(e.mod.Members[0])
+		if err != nil {
+			return err
+		}
+		at := e.commentsStart(span.Start.Offset)
+		e.add(at, at, text+e.newline+e.newline)
+		return nil
+	}
+
+	return e.insertText(text)
+}
+
+func (e *Editor) insertAtEnd(n ast.Node) error {
+	text, err := e.print(n, len(e.src))
+	if err != nil {
+		return err
+	}
+	return e.insertText(text)
+}
+
+// insertText appends text to the source code as a new paragraph.
+func (e *Editor) insertText(text string) error {
+	trimmed := bytes.TrimRight(e.src, " \t\r\n")
+	if len(trimmed) == 0 {
+		e.add(0, len(e.src), text+e.newline)
+		return nil
+	}
+	e.add(len(trimmed), len(e.src), e.newline+e.newline+text+e.newline)
+	return nil
+}
+
+func (e *Editor) insertInEmptyBody(body *ast.ObjectBody, n ast.Node) error {
+	span, err := e.span(body)
+	if err != nil {
+		return err
+	}
+
+	closing := span.End.Offset - 1
+	indent := e.indentation(closing)
+	text, err := e.print(n, closing)
+	if err != nil {
+		return err
+	}
+	text = indent + "  " + indentLines(text, "  ")
+
+	if e.lineStartsAt(closing) {
+		at := e.lineStart(closing)
+		e.add(at, at, text+e.newline)
+	} else {
+		e.add(closing, closing, e.newline+text+e.newline+indent)
+	}
+	return nil
+}
+
+// inline reports whether n starts and ends in the same line.
+func (e *Editor) inline(n ast.Node) bool {
+	span := ast.Position(n)
+	return span.Start.Line == span.End.Line
+}
+
+// find returns the index of the first token of kind in span that is not
+// nested in brackets, or -1.
+func (e *Editor) find(span ast.Span, kind token.Kind) int {
+	depth := 0
+	for i, tok := range e.toks {
+		if tok.Pos.Offset < span.Start.Offset {
+			continue
+		}
+		if tok.Pos.Offset >= span.End.Offset {
+			break
+		}
+		switch tok.Kind {
+		case token.LPAREN, token.LBRACK, token.LBRACE, token.INTERPOLATION_START:
+			depth++
+		case token.LPRED:
+			depth += 2
+		case token.RPAREN, token.RBRACK, token.RBRACE, token.INTERPOLATION_END:
+			depth--
+		case kind:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// commentsStart returns the start of the comments in the lines right above
+// offset, or offset if there are none.
+func (e *Editor) commentsStart(offset int) int {
+	start := offset
+	i := sort.Search(len(e.toks), func(i int) bool { return e.toks[i].Pos.Offset >= offset })
+	for i--; i >= 0; i-- {
+		tok := e.toks[i]
+		if tok.Kind != token.LINE_COMMENT && tok.Kind != token.BLOCK_COMMENT {
+			break
+		}
+		line := e.lineOf(start)
+		if tok.End.Line != line-1 || !e.lineStartsAt(tok.Pos.Offset) {
+			break
+		}
+		start = tok.Pos.Offset
+	}
+	return start
+}
+
+func (e *Editor) lineOf(offset int) int {
+	return bytes.Count(e.src[:offset], []byte{'\n'}) + 1
+}
+
+func (e *Editor) lineStart(offset int) int {
+	return bytes.LastIndexByte(e.src[:offset], '\n') + 1
+}
+
+// lineStartsAt reports whether only whitespace precedes offset in its line.
+func (e *Editor) lineStartsAt(offset int) bool {
+	return len(bytes.TrimLeft(e.src[e.lineStart(offset):offset], " \t")) == 0
+}
+
+func (e *Editor) indentation(offset int) string {
+	line := e.src[e.lineStart(offset):]
+	n := len(line) - len(bytes.TrimLeft(line, " \t"))
+	return string(line[:n])
+}
+
+// endOfLine returns the end of the line of offset, as in lineEnd, if only
+// whitespace and comments follow offset in it, or offset otherwise.
+func (e *Editor) endOfLine(offset int) int {
+	end := offset
+	for end < len(e.src) && (e.src[end] == ' ' || e.src[end] == '\t') {
+		end++
+	}
+	if bytes.HasPrefix(e.src[end:], []byte("//")) {
+		end = e.lineEnd(end)
+	}
+	if end == e.lineEnd(end) {
+		return end
+	}
+	return offset
+}
+
+// lineEnd returns the offset of the line ending of the line of offset, or
+// the end of the source code if it is the last line.
+func (e *Editor) lineEnd(offset int) int {
+	i := bytes.IndexByte(e.src[offset:], '\n')
+	if i < 0 {
+		return len(e.src)
+	}
+	end := offset + i
+	if end > offset && e.src[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// nextLine returns the start of the line after the line of offset, or the
+// end of the source code if it is the last line.
+func (e *Editor) nextLine(offset int) int {
+	if i := bytes.IndexByte(e.src[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(e.src)
+}
+
+// blankLine reports whether the line starting at offset is empty.
+func (e *Editor) blankLine(offset int) bool {
+	line := e.src[offset:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+// closesBody reports whether the line starting at offset starts with '}'.
+func (e *Editor) closesBody(offset int) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(e.src[offset:], " \t"), []byte{'}'})
+}
+
+func indentLines(text, indent string) string {
+	lines := bytes.Split([]byte(text), []byte{'\n'})
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) > 0 {
+			lines[i] = append([]byte(indent), lines[i]...)
+		}
+	}
+	return string(bytes.Join(lines, []byte{'\n'}))
+}
+
+func nodes[N ast.Node](members []N) []ast.Node {
+	res := make([]ast.Node, len(members))
+	for i, member := range members {
+		res[i] = member
+	}
+	return res
+}
+
+func insert[N any](members []N, index int, member N) []N {
+	res := make([]N, 0, len(members)+1)
+	res = append(res, members[:index]...)
+	res = append(res, member)
+	return append(res, members[index:]...)
+}
@@ -0,0 +1,305 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/pauloborges/balsamic/parser"
+)
+
+var src = stringsutil.StripMargin(`
+	|module config
+	|
+	|import "base.pkl"
+	|import "extra.pkl" // Unused.
+	|
+	|/// The port.
+	|port: Int = 8080 // Hand tuned.
+	|
+	|hosts {
+	|  // Primary.
+	|  ["a"]   =   "10.0.0.1"
+	|  ["b"] = "10.0.0.2"
+	|}
+	|
+	|empty {}
+	|
+	|class Server {
+	|  name: String
+	|}
+	|
+	|timeout: Duration
+`)
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(e *Editor, m *ast.Module) error
+		res  string
+	}{
+		{
+			name: "set class property expression",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.SetExpression(m.Members[0], ast.IntExpression(9090))
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -7 +7 @@
+				|port: Int = 9090 // Hand tuned.
+			`),
+		},
+		{
+			name: "set expression of type-only property",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.SetExpression(m.Members[4], &ast.MemberAccessExpression{Name: "defaultTimeout"})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -21 +21 @@
+				|timeout: Duration = defaultTimeout
+			`),
+		},
+		{
+			name: "set expression replacing body",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.SetExpression(m.Members[2], &ast.NewExpression{
+					Body: &ast.ObjectBody{
+						Members: ast.ObjectMembers{
+							&ast.ObjectElement{Value: ast.IntExpression(1)},
+						},
+					},
+				})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -15 +15,3 @@
+				|empty = new {
+				|  1
+				|}
+			`),
+		},
+		{
+			name: "set object entry value",
+			edit: func(e *Editor, m *ast.Module) error {
+				entry := m.Members[1].(*ast.ClassProperty).Body.Members[0]
+				return e.SetExpression(entry, ast.StringExpression("10.0.0.3"))
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -11 +11 @@
+				|  ["a"]   =   "10.0.0.3"
+			`),
+		},
+		{
+			name: "insert object entry",
+			edit: func(e *Editor, m *ast.Module) error {
+				body := m.Members[1].(*ast.ClassProperty).Body
+				return e.Insert(body, 2, &ast.ObjectEntry{
+					Key:   ast.StringExpression("c"),
+					Value: ast.StringExpression("10.0.0.4"),
+				})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -13,0 +13 @@
+				|  ["c"] = "10.0.0.4"
+			`),
+		},
+		{
+			name: "insert object entry before commented entry",
+			edit: func(e *Editor, m *ast.Module) error {
+				body := m.Members[1].(*ast.ClassProperty).Body
+				return e.Insert(body, 0, &ast.ObjectEntry{
+					Key:   ast.StringExpression("z"),
+					Value: ast.StringExpression("10.0.0.0"),
+				})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -10,0 +10 @@
+				|  ["z"] = "10.0.0.0"
+			`),
+		},
+		{
+			name: "insert in inline body",
+			edit: func(e *Editor, m *ast.Module) error {
+				body := m.Members[2].(*ast.ClassProperty).Body
+				return e.Insert(body, 0, &ast.ObjectElement{Value: ast.IntExpression(1)})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -15 +15,3 @@
+				|empty {
+				|  1
+				|}
+			`),
+		},
+		{
+			name: "insert class member",
+			edit: func(e *Editor, m *ast.Module) error {
+				class := m.Members[3].(*ast.Class)
+				return e.Insert(class, 1, &ast.ClassProperty{
+					Name: "port",
+					Type: &ast.DeclaredType{Name: "Int"},
+				})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -19,0 +19,2 @@
+				|
+				|  port: Int
+			`),
+		},
+		{
+			name: "insert module member",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.Insert(m, 1, &ast.ClassProperty{Name: "debug", Expression: ast.ExpressionNull})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -9,0 +9,2 @@
+				|debug = null
+				|
+			`),
+		},
+		{
+			name: "insert import",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.Insert(m, 2, &ast.ImportClause{Path: "more.pkl"})
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -5,0 +5 @@
+				|import "more.pkl"
+			`),
+		},
+		{
+			name: "remove import",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.Remove(m.Imports[1])
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -4 +4,0 @@
+			`),
+		},
+		{
+			name: "remove object entry with its comment",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.Remove(m.Members[1].(*ast.ClassProperty).Body.Members[0])
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -10,2 +10,0 @@
+			`),
+		},
+		{
+			name: "remove last module member",
+			edit: func(e *Editor, m *ast.Module) error {
+				return e.Remove(m.Members[4])
+			},
+			res: stringsutil.StripMargin(`
+				|@@ -21 +21,0 @@
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := NewEditor("config.pkl", []byte(src))
+			assert.NoError(t, err)
+
+			assert.NoError(t, test.edit(e, e.Module()))
+
+			res, err := e.Bytes()
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, diff(src, string(res)))
+
+			_, err = parser.ParseModule(res)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestEditorCRLF(t *testing.T) {
+	edit := func(e *Editor, m *ast.Module) error {
+		hosts := m.Members[1].(*ast.ClassProperty).Body
+		empty := m.Members[2].(*ast.ClassProperty).Body
+		return errors.Join(
+			e.Insert(hosts, 2, &ast.ObjectEntry{Key: ast.StringExpression("new"), Value: ast.IntExpression(3)}),
+			e.Insert(empty, 0, &ast.ObjectElement{Value: ast.IntExpression(1)}),
+			e.Insert(m, 1, &ast.ClassProperty{Name: "debug", Expression: ast.ExpressionNull}),
+			e.SetExpression(m.Members[0], &ast.NewExpression{
+				Body: &ast.ObjectBody{
+					Members: ast.ObjectMembers{&ast.ObjectProperty{Name: "value", Value: ast.IntExpression(9090)}},
+				},
+			}),
+			e.Remove(m.Imports[1]),
+		)
+	}
+
+	lf, err := NewEditor("config.pkl", []byte(src))
+	assert.NoError(t, err)
+	assert.NoError(t, edit(lf, lf.Module()))
+	lfRes, err := lf.Bytes()
+	assert.NoError(t, err)
+
+	crlfSrc := strings.ReplaceAll(src, "\n", "\r\n")
+	crlf, err := NewEditor("config.pkl", []byte(crlfSrc))
+	assert.NoError(t, err)
+	assert.NoError(t, edit(crlf, crlf.Module()))
+	crlfRes, err := crlf.Bytes()
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, src, string(lfRes))
+	assert.Equal(t, strings.ReplaceAll(string(lfRes), "\n", "\r\n"), string(crlfRes))
+}
+
+func TestEditorErrors(t *testing.T) {
+	e, err := NewEditor("config.pkl", []byte(src))
+	assert.NoError(t, err)
+	m := e.Module()
+
+	assert.ErrorIs(t, e.Replace(&ast.ClassProperty{Name: "foo"}, ast.IntExpression(1)), ErrNoPosition)
+	assert.EqualError(t, e.SetExpression(m.Imports[0], ast.IntExpression(1)), "edit: can not set the expression of *ast.ImportClause")
+	assert.EqualError(t, e.Insert(m, 9, &ast.ClassProperty{Name: "foo"}), "edit: index 9 out of range")
+	assert.EqualError(t, e.Insert(m.Members[3], 0, &ast.ObjectElement{}), "edit: can not insert *ast.ObjectElement in a class")
+}
+
+func TestApply(t *testing.T) {
+	res, err := Apply([]byte("foo = 1"), []Edit{
+		{Start: 6, End: 7, Text: "2"},
+		{Start: 0, End: 0, Text: "local "},
+		{Start: 0, End: 0, Text: "const "},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "local const foo = 2", string(res))
+
+	_, err = Apply([]byte("foo = 1"), []Edit{
+		{Start: 0, End: 5, Text: "bar"},
+		{Start: 4, End: 7, Text: "baz"},
+	})
+	assert.EqualError(t, err, "edit: invalid or overlapping edit of 4:7")
+}
+
+// diff returns the lines changed from a to b, in a hunk with the line
+// numbers of a and b.
+func diff(a, b string) string {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(al) && prefix < len(bl) && al[prefix] == bl[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(al)-prefix && suffix < len(bl)-prefix &&
+		al[len(al)-1-suffix] == bl[len(bl)-1-suffix] {
+		suffix++
+	}
+
+	lines := func(n int) string {
+		if n == 1 {
+			return strconv.Itoa(prefix + 1)
+		}
+		return fmt.Sprintf("%d,%d", prefix+1, n)
+	}
+
+	hunk := fmt.Sprintf("@@ -%s +%s @@", lines(len(al)-prefix-suffix), lines(len(bl)-prefix-suffix))
+	return strings.Join(append([]string{hunk}, bl[prefix:len(bl)-suffix]...), "\n")
+}
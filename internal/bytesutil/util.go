@@ -2,12 +2,67 @@ package bytesutil
 
 import (
 	"bytes"
+	"io"
 )
 
 // Buffer is a wrapper around bytes.Buffer that provides additional
 // functionality.
+//
+// A buffer created by NewWriterBuffer writes its bytes to a writer when
+// flushed. Its length, and the offsets it is truncated to, count the flushed
+// bytes too.
 type Buffer struct {
 	bytes.Buffer
+
+	w       io.Writer
+	flushed int
+	err     error
+}
+
+// NewWriterBuffer returns a buffer that writes its bytes to w when flushed.
+func NewWriterBuffer(w io.Writer) *Buffer {
+	return &Buffer{w: w}
+}
+
+// Len returns the number of bytes written to the buffer, including the
+// flushed ones.
+func (b *Buffer) Len() int {
+	return b.flushed + b.Buffer.Len()
+}
+
+// Truncate discards all but the first n bytes written to the buffer. It
+// panics if the bytes to discard were flushed.
+func (b *Buffer) Truncate(n int) {
+	if n < b.flushed {
+		panic("bytesutil: truncation of flushed bytes")
+	}
+	b.Buffer.Truncate(n - b.flushed)
+}
+
+// Buffered returns the number of bytes not flushed yet.
+func (b *Buffer) Buffered() int {
+	return b.Buffer.Len()
+}
+
+// Flush writes the bytes not flushed yet to the writer of the buffer, if it
+// has one. Once writing fails, Flush does nothing and Err returns the error.
+func (b *Buffer) Flush() {
+	if b.w == nil || b.err != nil {
+		return
+	}
+	n, err := b.Buffer.WriteTo(b.w)
+	b.flushed += int(n)
+	b.err = err
+}
+
+// Flushed returns the number of bytes written to the writer of the buffer.
+func (b *Buffer) Flushed() int64 {
+	return int64(b.flushed)
+}
+
+// Err returns the error writing to the writer of the buffer, if any.
+func (b *Buffer) Err() error {
+	return b.err
 }
 
 // WriteWithSuffix writes the bytes from p to the buffer, followed by the
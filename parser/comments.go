@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/token"
+)
+
+// memberStart returns the start of the member at the current token,
+// including its doc comments.
+func (p *parser) memberStart() token.Pos {
+	if len(p.docs) > 0 {
+		return p.docs[0].Pos
+	}
+	return p.tok.Pos
+}
+
+// memberComments takes the comments collected before a member starting at
+// start. The comments right above the member, with no blank line in between,
+// are returned as its leading comments, and the others as standalone
+// members.
+func (p *parser) memberComments(start token.Pos) (standalone []ast.Comment, leading ast.Comments) {
+	n := 0
+	for n < len(p.comments) && p.comments[n].Pos.Offset < start.Offset {
+		n++
+	}
+	toks := p.comments[:n]
+	p.comments = p.comments[n:]
+
+	i := len(toks)
+	line := start.Line
+	for i > 0 && toks[i-1].End.Line >= line-1 {
+		i--
+		line = toks[i].Pos.Line
+	}
+
+	return groupComments(toks[:i]), groupComments(toks[i:])
+}
+
+// trailingComment drops the comments collected inside the member just
+// parsed, and takes the comments at the end of its last line. A trailing
+// comment can only be a line comment, so the block comments there are kept
+// as part of its text, as in "// /* a */ b" for "/* a */ // b".
+func (p *parser) trailingComment() ast.LineComment {
+	p.dropComments()
+
+	n := 0
+	for n < len(p.comments) && p.comments[n].Pos.Line == p.prevEnd.Line && p.comments[n].End.Line == p.prevEnd.Line {
+		n++
+	}
+	toks := p.comments[:n]
+	p.comments = p.comments[n:]
+
+	if len(toks) == 1 && toks[0].Kind == token.LINE_COMMENT {
+		return ast.LineComment(lineCommentText(toks[0]))
+	}
+	texts := make([]string, len(toks))
+	for i, tok := range toks {
+		texts[i] = tok.Text
+	}
+	return ast.LineComment(strings.TrimRight(strings.Join(texts, " "), " \t\r"))
+}
+
+// dropComments drops the comments collected before the end of the last
+// consumed token.
+func (p *parser) dropComments() {
+	n := 0
+	for n < len(p.comments) && p.comments[n].Pos.Offset < p.prevEnd.Offset {
+		n++
+	}
+	p.comments = p.comments[n:]
+}
+
+// standaloneComments takes the comments collected, as standalone members.
+func (p *parser) standaloneComments() []ast.Comment {
+	comments := groupComments(p.comments)
+	p.comments = nil
+	return comments
+}
+
+// groupComments converts comment tokens to ast comments. Line comments in
+// consecutive lines are merged into a single ast.LineComment.
+func groupComments(toks []token.Token) []ast.Comment {
+	var comments []ast.Comment
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		if tok.Kind == token.BLOCK_COMMENT {
+			comments = append(comments, ast.BlockComment(blockCommentText(tok)))
+			continue
+		}
+
+		lines := []string{lineCommentText(tok)}
+		for i+1 < len(toks) && toks[i+1].Kind == token.LINE_COMMENT && toks[i+1].Pos.Line == toks[i].Pos.Line+1 {
+			i++
+			lines = append(lines, lineCommentText(toks[i]))
+		}
+		comments = append(comments, ast.LineComment(strings.Join(lines, "\n")))
+	}
+	return comments
+}
+
+func lineCommentText(tok token.Token) string {
+	text := strings.TrimPrefix(tok.Text, "//")
+	text = strings.TrimPrefix(text, " ")
+	return strings.TrimRight(text, " \t\r")
+}
+
+// blockCommentText returns the text of a block comment without the
+// delimiters, the blank lines around it and the indentation of its lines.
+func blockCommentText(tok token.Token) string {
+	text := strings.TrimSuffix(strings.TrimPrefix(tok.Text, "/*"), "*/")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendComments appends standalone comments to a list of members.
+func appendComments[M ast.Node](members []M, comments []ast.Comment) []M {
+	for _, comment := range comments {
+		members = append(members, any(comment).(M))
+	}
+	return members
+}
+
+// setComments attaches comments to a member.
+func setComments(n ast.Node, leading ast.Comments, trailing ast.LineComment) {
+	switch n := n.(type) {
+	case *ast.ImportClause:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.Class:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ClassProperty:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ClassMethod:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.TypeAlias:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ObjectProperty:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ObjectMethod:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ObjectEntry:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ObjectElement:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ObjectSpread:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.MemberPredicate:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.ForGenerator:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	case *ast.WhenGenerator:
+		n.LeadingComments, n.TrailingComment = leading, trailing
+	}
+}
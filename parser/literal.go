@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseInt parses a Pkl integer literal, which may be written in decimal,
+// hexadecimal (0x), binary (0b) or octal (0o) and contain underscores. The
+// literal may be preceded by a minus, which is checked along with it against
+// the range of int64.
+func parseInt(lit string) (int64, error) {
+	lit = strings.ReplaceAll(lit, "_", "")
+
+	sign := ""
+	if strings.HasPrefix(lit, "-") {
+		sign, lit = "-", lit[1:]
+	}
+
+	base := 10
+	if len(lit) > 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+		if base != 10 {
+			lit = lit[2:]
+		}
+	}
+
+	return strconv.ParseInt(sign+lit, base, 64)
+}
+
+// parseFloat parses a Pkl floating-point literal.
+func parseFloat(lit string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(lit, "_", ""), 64)
+}
+
+// unquote decodes a Pkl string literal without interpolation, including
+// custom-delimited (#"..."#) and multi-line ("""...""") strings.
+func unquote(lit string) (string, error) {
+	pounds := 0
+	for pounds < len(lit) && lit[pounds] == '#' {
+		pounds++
+	}
+	body := lit[pounds : len(lit)-pounds]
+
+	quotes := 1
+	multiline := strings.HasPrefix(body, `"""`)
+	if multiline {
+		quotes = 3
+	}
+
+	texts, err := unquoteParts([]string{body[quotes : len(body)-quotes]}, pounds, multiline)
+	if err != nil {
+		return "", err
+	}
+	return texts[0], nil
+}
+
+// unquoteParts decodes the text of a Pkl string literal with the given number
+// of # characters in its delimiters, split by its interpolations.
+func unquoteParts(texts []string, pounds int, multiline bool) ([]string, error) {
+	if multiline {
+		var err error
+		texts, err = stripMultilineIndent(texts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	escape := `\` + strings.Repeat("#", pounds)
+	res := make([]string, len(texts))
+	for i, text := range texts {
+		s, err := unescape(text, escape)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+// stripMultilineIndent removes the leading and trailing line breaks of a
+// multi-line string and the indentation of the closing delimiter from every
+// line. The text of the string is split by its interpolations, which are part
+// of the lines they are in.
+func stripMultilineIndent(texts []string) ([]string, error) {
+	texts = slices.Clone(texts)
+	for i, text := range texts {
+		texts[i] = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+
+	if !strings.HasPrefix(texts[0], "\n") {
+		return nil, errors.New("multi-line string must start with a line break")
+	}
+	texts[0] = texts[0][1:]
+
+	n := len(texts) - 1
+	last := strings.LastIndexByte(texts[n], '\n')
+	if last < 0 {
+		if n > 0 || strings.TrimLeft(texts[n], " \t") != "" {
+			return nil, errors.New("closing delimiter of multi-line string must be on its own line")
+		}
+		return []string{""}, nil
+	}
+
+	indent := texts[n][last+1:]
+	if strings.TrimLeft(indent, " \t") != "" {
+		return nil, errors.New("closing delimiter of multi-line string must be on its own line")
+	}
+	texts[n] = texts[n][:last]
+
+	line := 0
+	for i, text := range texts {
+		lines := strings.Split(text, "\n")
+		for j, l := range lines {
+			// The text after an interpolation does not start a line.
+			if i > 0 && j == 0 {
+				continue
+			}
+			line++
+
+			// Lines with an interpolation are not blank.
+			blank := strings.TrimLeft(l, " \t") == "" && (j < len(lines)-1 || i == n)
+			if blank {
+				lines[j] = strings.TrimPrefix(l, indent)
+				continue
+			}
+			if !strings.HasPrefix(l, indent) {
+				return nil, fmt.Errorf("line %d of multi-line string must be indented at least as much as the closing delimiter", line)
+			}
+			lines[j] = l[len(indent):]
+		}
+		texts[i] = strings.Join(lines, "\n")
+	}
+
+	return texts, nil
+}
+
+func unescape(s, escape string) (string, error) {
+	var b strings.Builder
+
+	for len(s) > 0 {
+		i := strings.Index(s, escape)
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i+len(escape):]
+
+		if s == "" {
+			return "", errors.New("invalid escape sequence at end of string")
+		}
+
+		switch s[0] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '"':
+			b.WriteByte('"')
+		case '\\':
+			b.WriteByte('\\')
+		case 'u':
+			end := strings.IndexByte(s, '}')
+			if len(s) < 3 || s[1] != '{' || end < 0 {
+				return "", errors.New("invalid unicode escape sequence")
+			}
+			code, err := strconv.ParseUint(s[2:end], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid unicode escape sequence %q", escape+s[:end+1])
+			}
+			b.WriteRune(rune(code))
+			s = s[end+1:]
+			continue
+		default:
+			r, _ := utf8.DecodeRuneInString(s)
+			return "", fmt.Errorf("invalid escape sequence %q", escape+string(r))
+		}
+		s = s[1:]
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,2202 @@
+// Package parser implements a parser for Pkl source code. It produces the
+// nodes defined in package ast, so parsed modules can be modified and
+// printed back with their Marshal methods.
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/scanner"
+	"github.com/pauloborges/balsamic/token"
+)
+
+// Error is a syntax error found while parsing Pkl source code.
+type Error struct {
+	Filename string
+	Line     int
+	Column   int
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Severity is the severity of a Diagnostic. The parser only reports errors
+// for now.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Diagnostic is a problem found while parsing Pkl source code.
+type Diagnostic struct {
+	Filename string
+	Line     int
+	Column   int
+	Severity Severity
+	Msg      string
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", d.Filename, d.Line, d.Column, d.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Msg)
+}
+
+// Diagnostics is a list of diagnostics, sorted by position.
+type Diagnostics []*Diagnostic
+
+func (d Diagnostics) Error() string {
+	switch len(d) {
+	case 0:
+		return "no diagnostics"
+	case 1:
+		return d[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more diagnostics)", d[0], len(d)-1)
+}
+
+// Mode controls the parser behavior.
+type Mode uint
+
+const (
+	// AllErrors makes the parser report every syntax error instead of
+	// stopping at the first one. After an error the parser skips to the next
+	// module, class or object member, leaving the broken member out of the
+	// tree.
+	AllErrors Mode = 1 << iota
+	// ParseComments makes the parser keep the line and block comments. The
+	// comments right above a member or at the end of its last line are
+	// attached to it, and the others are kept as standalone members. Block
+	// comments at the end of the last line of a member are kept in the text
+	// of its trailing line comment. Comments anywhere else, such as inside
+	// expressions, are dropped.
+	ParseComments
+)
+
+// ParseModule parses the source code of a Pkl module.
+func ParseModule(src []byte) (*ast.Module, error) {
+	return ParseFile("", src)
+}
+
+// ParseFile parses the source code of a Pkl module read from filename. The
+// filename is only used to annotate positions and errors.
+func ParseFile(filename string, src []byte) (*ast.Module, error) {
+	return ParseFileMode(filename, src, 0)
+}
+
+// ParseFileMode is like ParseFile, but its behavior is controlled by mode.
+//
+// With AllErrors, the returned module is never nil: it holds every member
+// that could be parsed, and the error, if any, is the Diagnostics found.
+// Otherwise the error is the first *Error found.
+func ParseFileMode(filename string, src []byte, mode Mode) (*ast.Module, error) {
+	p, err := newParser(filename, src, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := p.parseModule()
+	if mode&AllErrors == 0 {
+		return m, err
+	}
+
+	if len(p.diags) > 0 {
+		return m, p.diags
+	}
+	return m, nil
+}
+
+// ParseExpression parses a single Pkl expression, such as
+// "foo.bar(1) ?? 2".
+func ParseExpression(src string) (ast.Expression, error) {
+	p, err := newParser("", []byte(src), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind != token.EOF {
+		return nil, p.unexpected("end of expression")
+	}
+
+	return expr, nil
+}
+
+// ParseType parses a single Pkl type, such as "Listing<String(!isEmpty)>?".
+func ParseType(src string) (ast.Type, error) {
+	p, err := newParser("", []byte(src), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind != token.EOF {
+		return nil, p.unexpected("end of type")
+	}
+
+	return typ, nil
+}
+
+type parser struct {
+	filename string
+	src      []byte
+	mode     Mode
+
+	toks []token.Token
+	pos  int
+	tok  token.Token
+
+	// End of the last consumed token.
+	prevEnd token.Pos
+
+	// Doc comments skipped right before the current token.
+	docs []token.Token
+	// Line and block comments skipped and not attached to a node yet, in
+	// ParseComments mode.
+	comments []token.Token
+
+	// Problems found so far, in AllErrors mode.
+	diags Diagnostics
+}
+
+func newParser(filename string, src []byte, mode Mode) (*parser, error) {
+	p := &parser{filename: filename, src: src, mode: mode}
+
+	var scanMode scanner.Mode
+	if mode&ParseComments != 0 {
+		scanMode = scanner.ScanComments
+	}
+
+	var firstErr error
+	p.toks = scanner.Tokenize(src, func(pos token.Pos, msg string) {
+		err := &Error{Filename: filename, Line: pos.Line, Column: pos.Column, Msg: msg}
+		if firstErr == nil {
+			firstErr = err
+		}
+		p.report(err)
+	}, scanMode)
+
+	if firstErr != nil && mode&AllErrors == 0 {
+		return nil, firstErr
+	}
+
+	p.pos = -1
+	p.next()
+
+	return p, nil
+}
+
+// next advances to the next token, collecting the comments in between.
+func (p *parser) next() {
+	if p.pos >= 0 {
+		p.prevEnd = p.tok.End
+	}
+	p.docs = nil
+	for {
+		if p.pos < len(p.toks)-1 {
+			p.pos++
+		}
+		p.tok = p.toks[p.pos]
+		switch p.tok.Kind {
+		case token.DOC_COMMENT:
+			p.docs = append(p.docs, p.tok)
+		case token.LINE_COMMENT, token.BLOCK_COMMENT:
+			p.comments = append(p.comments, p.tok)
+		default:
+			return
+		}
+	}
+}
+
+func isSkipped(kind token.Kind) bool {
+	return kind == token.DOC_COMMENT || kind == token.LINE_COMMENT || kind == token.BLOCK_COMMENT
+}
+
+// peek returns the n-th token after the current one, skipping comments.
+func (p *parser) peek(n int) token.Token {
+	i := p.pos
+	for n > 0 && i < len(p.toks)-1 {
+		i++
+		if !isSkipped(p.toks[i].Kind) {
+			n--
+		}
+	}
+	return p.toks[i]
+}
+
+// state is a snapshot of the parser position.
+type state struct {
+	pos      int
+	prevEnd  token.Pos
+	docs     []token.Token
+	comments []token.Token
+	diags    int
+}
+
+// save and restore allow the parser to backtrack when a construct can only
+// be told apart from another after looking at an arbitrary number of tokens.
+func (p *parser) save() state {
+	return state{
+		pos:      p.pos,
+		prevEnd:  p.prevEnd,
+		docs:     p.docs,
+		comments: p.comments,
+		diags:    len(p.diags),
+	}
+}
+
+func (p *parser) restore(s state) {
+	p.pos = s.pos
+	p.tok = p.toks[s.pos]
+	p.prevEnd = s.prevEnd
+	p.docs = s.docs
+	p.comments = s.comments
+	p.diags = p.diags[:s.diags]
+}
+
+func (p *parser) astPos(pos token.Pos) ast.Pos {
+	return ast.Pos{
+		Filename: p.filename,
+		Offset:   pos.Offset,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+// spanFrom returns the span from start to the end of the last consumed
+// token.
+func (p *parser) spanFrom(start token.Pos) ast.Span {
+	return ast.Span{Start: p.astPos(start), End: p.astPos(p.prevEnd)}
+}
+
+func (p *parser) errorf(pos token.Pos, format string, args ...any) error {
+	return &Error{
+		Filename: p.filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Msg:      fmt.Sprintf(format, args...),
+	}
+}
+
+// report records err as a diagnostic, keeping the list sorted. An error at
+// the same position of a previous one is usually a consequence of it, such
+// as the parser choking on an invalid character, so it is dropped.
+func (p *parser) report(err error) {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Filename: p.filename, Line: p.tok.Pos.Line, Column: p.tok.Pos.Column, Msg: err.Error()}
+	}
+
+	i := len(p.diags)
+	for i > 0 && (p.diags[i-1].Line > e.Line ||
+		p.diags[i-1].Line == e.Line && p.diags[i-1].Column > e.Column) {
+		i--
+	}
+	if i > 0 && p.diags[i-1].Line == e.Line && p.diags[i-1].Column == e.Column {
+		return
+	}
+
+	diag := &Diagnostic{
+		Filename: e.Filename,
+		Line:     e.Line,
+		Column:   e.Column,
+		Severity: SeverityError,
+		Msg:      e.Msg,
+	}
+	p.diags = append(p.diags, nil)
+	copy(p.diags[i+1:], p.diags[i:])
+	p.diags[i] = diag
+}
+
+// recoverMember handles err, found while parsing the member starting at the
+// token index from. In AllErrors mode it reports err and skips the rest of
+// the member, otherwise it returns err unchanged.
+func (p *parser) recoverMember(err error, from int, nested bool) error {
+	if p.mode&AllErrors == 0 {
+		return err
+	}
+	p.report(err)
+	p.skipMember(from, nested)
+	return nil
+}
+
+// skipMember advances past the member starting at the token index from,
+// which failed to parse. The next member is assumed to start at the first
+// line, after the error, that is not nested in brackets opened by the broken
+// member or that is indented no deeper than it. In a nested body, the
+// closing brace of the body and semicolons also end the member.
+func (p *parser) skipMember(from int, nested bool) {
+	errPos := p.pos
+	column := p.toks[from].Pos.Column
+
+	stop := len(p.toks) - 1
+	depth := 0
+loop:
+	for i := from; i < len(p.toks); i++ {
+		tok := p.toks[i]
+
+		switch tok.Kind {
+		case token.EOF:
+			stop = i
+			break loop
+		case token.RPAREN, token.RBRACE, token.RBRACK:
+			depth--
+			if depth < 0 {
+				if nested && tok.Kind == token.RBRACE {
+					stop = i
+					break loop
+				}
+				depth = 0
+			}
+			continue
+		case token.SEMICOLON:
+			if nested && depth == 0 && i > from && i >= errPos {
+				stop = i
+				break loop
+			}
+		}
+
+		if i > from && i >= errPos && tok.Newline && (depth == 0 || tok.Pos.Column <= column) {
+			stop = i
+			break
+		}
+
+		switch tok.Kind {
+		case token.LPAREN, token.LBRACE, token.LBRACK:
+			depth++
+		case token.LPRED:
+			depth += 2
+		}
+	}
+
+	// Skip at least one token, so that recovering always makes progress.
+	if stop <= from && p.toks[from].Kind != token.EOF {
+		stop = from + 1
+	}
+
+	// Stop right before the token, so advancing to it collects its doc
+	// comments.
+	p.pos = stop - 1
+	if p.pos >= 0 {
+		p.tok = p.toks[p.pos]
+	}
+	p.comments = nil
+	p.next()
+}
+
+func describe(tok token.Token) string {
+	switch tok.Kind {
+	case token.EOF:
+		return "end of file"
+	case token.STRING_START:
+		return "string literal"
+	case token.DOC_COMMENT:
+		return "doc comment"
+	}
+	return "'" + tok.Text + "'"
+}
+
+func (p *parser) unexpected(expected string) error {
+	return p.errorf(p.tok.Pos, "expected %s, found %s", expected, describe(p.tok))
+}
+
+func (p *parser) expect(kind token.Kind) (token.Token, error) {
+	tok := p.tok
+	if tok.Kind != kind {
+		return tok, p.unexpected("'" + kind.String() + "'")
+	}
+	p.next()
+	return tok, nil
+}
+
+func (p *parser) got(kind token.Kind) bool {
+	if p.tok.Kind == kind {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// ----------------------------------------------------------------------------
+// Modules
+
+func (p *parser) parseModule() (*ast.Module, error) {
+	m := &ast.Module{}
+	start := p.toks[0].Pos
+
+	if p.tok.Kind == token.SHEBANG {
+		m.ShebangComment = ast.ShebangComment(strings.TrimSpace(strings.TrimPrefix(p.tok.Text, "#!")))
+		p.next()
+	}
+
+	from := p.pos
+	headerStart := p.memberStart()
+	header, err := p.parseModuleHeader(m)
+	if err != nil {
+		if err := p.recoverMember(err, from, false); err != nil {
+			return nil, err
+		}
+		header = nil
+	}
+	if m.Name != "" || m.ParentName != "" {
+		standalone, leading := p.memberComments(headerStart)
+		m.LeadingComments, m.HeaderComments = standalone, leading
+		p.dropComments()
+	}
+
+	for p.tok.Kind == token.IMPORT || p.tok.Kind == token.IMPORT_GLOB {
+		if header != nil && !header.empty() {
+			if err := p.recoverMember(p.unexpected("module member"), from, false); err != nil {
+				return nil, err
+			}
+			header = nil
+			continue
+		}
+		// The empty header parsed before the imports does not belong to the
+		// first member.
+		header = nil
+
+		from := p.pos
+		standalone, leading := p.memberComments(p.tok.Pos)
+		imp, err := p.parseImportClause()
+		if err != nil {
+			if err := p.recoverMember(err, from, false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		setComments(imp, append(ast.Comments(standalone), leading...), p.trailingComment())
+		m.Imports = append(m.Imports, imp)
+	}
+
+	for p.tok.Kind != token.EOF {
+		var standalone []ast.Comment
+		var leading ast.Comments
+
+		if header == nil {
+			from = p.pos
+			standalone, leading = p.memberComments(p.memberStart())
+			header, err = p.parseMemberHeader()
+			if err != nil {
+				m.Members = appendComments(m.Members, standalone)
+				if err := p.recoverMember(err, from, false); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		} else {
+			standalone, leading = p.memberComments(header.start)
+		}
+		m.Members = appendComments(m.Members, standalone)
+
+		member, err := p.parseModuleMember(header)
+		header = nil
+		if err != nil {
+			if err := p.recoverMember(err, from, false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		setComments(member, leading, p.trailingComment())
+		m.Members = append(m.Members, member)
+	}
+
+	if header != nil && !header.empty() {
+		if err := p.recoverMember(p.unexpected("module member"), from, false); err != nil {
+			return nil, err
+		}
+	}
+
+	m.Members = appendComments(m.Members, p.standaloneComments())
+
+	m.Span = ast.Span{Start: p.astPos(start), End: p.astPos(p.tok.End)}
+
+	return m, nil
+}
+
+// parseModuleHeader parses the module and amends or extends clauses into m.
+// It returns the member header it had to parse to find out whether there is
+// a module clause, or nil if the header belongs to the module.
+func (p *parser) parseModuleHeader(m *ast.Module) (*memberHeader, error) {
+	header, err := p.parseMemberHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.tok.Kind {
+	case token.MODULE:
+		p.next()
+		name, err := p.parseQualifiedIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		m.Name = name
+	case token.EXTENDS, token.AMENDS:
+		if len(header.modifiers) > 0 {
+			return nil, p.unexpected("'module'")
+		}
+	}
+
+	if p.tok.Kind == token.EXTENDS || p.tok.Kind == token.AMENDS {
+		m.ParentRelationship = ast.ModuleRelationship(p.tok.Text)
+		p.next()
+		parent, err := p.parseStringConstant()
+		if err != nil {
+			return nil, err
+		}
+		m.ParentName = parent
+	}
+
+	if m.Name != "" || m.ParentName != "" {
+		m.Docs = header.docs
+		m.Annotations = header.annotations
+		m.Modifiers = header.modifiers
+		return nil, nil
+	}
+
+	return header, nil
+}
+
+func (p *parser) parseImportClause() (*ast.ImportClause, error) {
+	start := p.tok.Pos
+	imp := &ast.ImportClause{Glob: p.tok.Kind == token.IMPORT_GLOB}
+	p.next()
+
+	path, err := p.parseStringConstant()
+	if err != nil {
+		return nil, err
+	}
+	imp.Path = path
+
+	if p.got(token.AS) {
+		alias, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		imp.Alias = string(alias)
+	}
+
+	imp.Span = p.spanFrom(start)
+
+	return imp, nil
+}
+
+// memberHeader holds the docs, annotations and modifiers preceding a
+// declaration.
+type memberHeader struct {
+	start       token.Pos
+	docs        ast.Docs
+	annotations ast.Annotations
+	// Start of the modifiers, or of the declaration keyword if there are
+	// none.
+	modifiersStart token.Pos
+	modifiers      ast.Modifiers
+}
+
+func (h *memberHeader) empty() bool {
+	return h.docs == "" && len(h.annotations) == 0 && len(h.modifiers) == 0
+}
+
+func (p *parser) parseMemberHeader() (*memberHeader, error) {
+	h := &memberHeader{start: p.memberStart()}
+	h.docs = p.parseDocs()
+
+	for p.tok.Kind == token.AT {
+		annotation, err := p.parseAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		h.annotations = append(h.annotations, annotation)
+	}
+
+	h.modifiersStart = p.tok.Pos
+	h.modifiers = p.parseModifiers()
+
+	return h, nil
+}
+
+// parseDocs returns the doc comments immediately preceding the current
+// token.
+func (p *parser) parseDocs() ast.Docs {
+	var lines []string
+	for _, tok := range p.docs {
+		line := strings.TrimPrefix(tok.Text, "///")
+		line = strings.TrimPrefix(line, " ")
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	p.docs = nil
+	return ast.Docs(strings.Join(lines, "\n"))
+}
+
+func (p *parser) parseAnnotation() (*ast.Annotation, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.AT); err != nil {
+		return nil, err
+	}
+
+	name, err := p.parseQualifiedIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	annotation := &ast.Annotation{Name: name}
+
+	if p.tok.Kind == token.LBRACE {
+		annotation.Body, err = p.parseObjectBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	annotation.Span = p.spanFrom(start)
+
+	return annotation, nil
+}
+
+func isModifier(kind token.Kind) bool {
+	switch kind {
+	case token.ABSTRACT, token.CONST, token.EXTERNAL, token.FIXED,
+		token.HIDDEN, token.LOCAL, token.OPEN:
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseModifiers() ast.Modifiers {
+	var modifiers ast.Modifiers
+	for isModifier(p.tok.Kind) {
+		modifiers = append(modifiers, ast.Modifier(p.tok.Text))
+		p.next()
+	}
+	return modifiers
+}
+
+func (p *parser) parseModuleMember(h *memberHeader) (ast.ModuleMember, error) {
+	switch p.tok.Kind {
+	case token.CLASS:
+		return p.parseClass(h)
+	case token.TYPEALIAS:
+		return p.parseTypeAlias(h)
+	case token.FUNCTION:
+		return p.parseClassMethod(h)
+	case token.IDENT:
+		return p.parseClassProperty(h)
+	}
+	return nil, p.unexpected("module member")
+}
+
+// ----------------------------------------------------------------------------
+// Classes and type aliases
+
+func (p *parser) parseClass(h *memberHeader) (*ast.Class, error) {
+	if _, err := p.expect(token.CLASS); err != nil {
+		return nil, err
+	}
+
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	class := &ast.Class{
+		Docs:        h.docs,
+		Annotations: h.annotations,
+		Modifiers:   h.modifiers,
+		Name:        name,
+	}
+
+	if p.tok.Kind == token.LSS {
+		class.TypeParameters, err = p.parseTypeParameters()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.got(token.EXTENDS) {
+		class.ParentName, err = p.parseQualifiedIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Kind == token.LSS {
+			class.ParentTypeParameters, err = p.parseParentTypeArguments()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.got(token.LBRACE) {
+		p.dropComments()
+		for p.tok.Kind != token.RBRACE && p.tok.Kind != token.EOF {
+			from := p.pos
+			standalone, leading := p.memberComments(p.memberStart())
+			class.Members = appendComments(class.Members, standalone)
+
+			member, err := p.parseClassMemberWithHeader()
+			if err != nil {
+				if err := p.recoverMember(err, from, true); err != nil {
+					return nil, err
+				}
+				// Semicolons do not separate class members, but end the
+				// broken one.
+				for p.got(token.SEMICOLON) {
+				}
+				continue
+			}
+			setComments(member, leading, p.trailingComment())
+			class.Members = append(class.Members, member)
+		}
+		class.Members = appendComments(class.Members, p.standaloneComments())
+		if _, err := p.expect(token.RBRACE); err != nil {
+			return nil, err
+		}
+	}
+
+	class.Span = p.spanFrom(h.start)
+
+	return class, nil
+}
+
+// parseParentTypeArguments parses the type arguments of a class parent.
+// ast.Class can only represent simple type names there.
+func (p *parser) parseParentTypeArguments() (ast.TypeParameters, error) {
+	start := p.tok.Pos
+
+	args, err := p.parseTypeArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(ast.TypeParameters, 0, len(args))
+	for _, arg := range args {
+		declared, ok := arg.(*ast.DeclaredType)
+		if !ok || len(declared.TypeParameters) > 0 {
+			return nil, p.errorf(start, "unsupported type argument in class parent")
+		}
+		params = append(params, &ast.TypeParameter{
+			Name: ast.Identifier(declared.Name),
+			Span: declared.Span,
+		})
+	}
+
+	return params, nil
+}
+
+func (p *parser) parseClassMemberWithHeader() (ast.ClassMember, error) {
+	header, err := p.parseMemberHeader()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseClassMember(header)
+}
+
+func (p *parser) parseClassMember(h *memberHeader) (ast.ClassMember, error) {
+	switch p.tok.Kind {
+	case token.FUNCTION:
+		return p.parseClassMethod(h)
+	case token.IDENT:
+		return p.parseClassProperty(h)
+	}
+	return nil, p.unexpected("class member")
+}
+
+func (p *parser) parseClassProperty(h *memberHeader) (*ast.ClassProperty, error) {
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	prop := &ast.ClassProperty{
+		Docs:        h.docs,
+		Annotations: h.annotations,
+		Modifiers:   h.modifiers,
+		Name:        name,
+	}
+
+	switch p.tok.Kind {
+	case token.COLON:
+		p.next()
+		prop.Type, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if p.got(token.ASSIGN) {
+			prop.Expression, err = p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case token.ASSIGN:
+		p.next()
+		prop.Expression, err = p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+	case token.LBRACE:
+		prop.Body, err = p.parseObjectBody()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Kind == token.LBRACE {
+			return nil, p.errorf(p.tok.Pos, "multiple object bodies are not supported in class properties")
+		}
+	default:
+		return nil, p.unexpected("':', '=' or '{'")
+	}
+
+	prop.Span = p.spanFrom(h.start)
+
+	return prop, nil
+}
+
+func (p *parser) parseMethodSignature(start token.Pos, modifiers ast.Modifiers) (*ast.MethodSignature, error) {
+	if _, err := p.expect(token.FUNCTION); err != nil {
+		return nil, err
+	}
+
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &ast.MethodSignature{Modifiers: modifiers, Name: name}
+
+	if p.tok.Kind == token.LSS {
+		sig.TypeParameters, err = p.parseTypeParameters()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(token.LPAREN); err != nil {
+		return nil, err
+	}
+	sig.Parameters, err = p.parseParameterList(token.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.RPAREN); err != nil {
+		return nil, err
+	}
+
+	if p.got(token.COLON) {
+		sig.Result, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sig.Span = p.spanFrom(start)
+
+	return sig, nil
+}
+
+func (p *parser) parseClassMethod(h *memberHeader) (*ast.ClassMethod, error) {
+	sig, err := p.parseMethodSignature(h.modifiersStart, h.modifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	method := &ast.ClassMethod{
+		Docs:        h.docs,
+		Annotations: h.annotations,
+		Signature:   sig,
+	}
+
+	if p.got(token.ASSIGN) {
+		method.Implementation, err = p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	method.Span = p.spanFrom(h.start)
+
+	return method, nil
+}
+
+func (p *parser) parseTypeAlias(h *memberHeader) (*ast.TypeAlias, error) {
+	if _, err := p.expect(token.TYPEALIAS); err != nil {
+		return nil, err
+	}
+
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	alias := &ast.TypeAlias{
+		Docs:        h.docs,
+		Annotations: h.annotations,
+		Modifiers:   h.modifiers,
+		Name:        name,
+	}
+
+	if p.tok.Kind == token.LSS {
+		alias.Parameters, err = p.parseTypeParameters()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(token.ASSIGN); err != nil {
+		return nil, err
+	}
+
+	alias.Type, err = p.parseType()
+	if err != nil {
+		return nil, err
+	}
+
+	alias.Span = p.spanFrom(h.start)
+
+	return alias, nil
+}
+
+// ----------------------------------------------------------------------------
+// Identifiers and parameters
+
+func (p *parser) parseIdentifier() (ast.Identifier, error) {
+	tok, err := p.expect(token.IDENT)
+	if err != nil {
+		return "", err
+	}
+	return ast.Identifier(strings.Trim(tok.Text, "`")), nil
+}
+
+func (p *parser) parseQualifiedIdentifier() (ast.QualifiedIdentifier, error) {
+	var parts []string
+
+	for {
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, string(name))
+
+		if p.tok.Kind != token.DOT {
+			break
+		}
+		p.next()
+	}
+
+	return ast.QualifiedIdentifier(strings.Join(parts, ".")), nil
+}
+
+// parseStringConstant parses a string literal without interpolation.
+func (p *parser) parseStringConstant() (string, error) {
+	start, err := p.expect(token.STRING_START)
+	if err != nil {
+		return "", err
+	}
+
+	for p.tok.Kind != token.STRING_END {
+		if p.tok.Kind == token.INTERPOLATION_START {
+			return "", p.errorf(p.tok.Pos, "string interpolation is not supported")
+		}
+		p.next()
+	}
+	end := p.tok
+	p.next()
+
+	// The scanner ends unterminated strings with an empty token.
+	if end.Text == "" {
+		return "", p.errorf(start.Pos, "string literal not terminated")
+	}
+
+	s, err := unquote(string(p.src[start.Pos.Offset:end.End.Offset]))
+	if err != nil {
+		return "", p.errorf(start.Pos, "%s", err)
+	}
+
+	return s, nil
+}
+
+// parseStringLiteral parses a string literal, which may have interpolated
+// expressions.
+func (p *parser) parseStringLiteral() (ast.Expression, error) {
+	start, err := p.expect(token.STRING_START)
+	if err != nil {
+		return nil, err
+	}
+	pounds := strings.Count(start.Text, "#")
+	multiline := strings.HasSuffix(start.Text, `"""`)
+
+	var texts []string
+	var interpolations []*ast.StringInterpolation
+	textStart := start.End.Offset
+	for p.tok.Kind != token.STRING_END {
+		if p.tok.Kind != token.INTERPOLATION_START {
+			p.next()
+			continue
+		}
+		texts = append(texts, string(p.src[textStart:p.tok.Pos.Offset]))
+
+		interpolationStart := p.tok.Pos
+		p.next()
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		end, err := p.expect(token.INTERPOLATION_END)
+		if err != nil {
+			return nil, err
+		}
+		interpolations = append(interpolations, &ast.StringInterpolation{
+			Expression: expr,
+			Span:       p.spanFrom(interpolationStart),
+		})
+		textStart = end.End.Offset
+	}
+	end := p.tok
+	p.next()
+
+	// The scanner ends unterminated strings with an empty token.
+	if end.Text == "" {
+		return nil, p.errorf(start.Pos, "string literal not terminated")
+	}
+	texts = append(texts, string(p.src[textStart:end.Pos.Offset]))
+
+	texts, err = unquoteParts(texts, pounds, multiline)
+	if err != nil {
+		return nil, p.errorf(start.Pos, "%s", err)
+	}
+
+	if len(interpolations) == 0 {
+		switch {
+		case multiline:
+			return &ast.MultiLineStringExpression{
+				Value:  texts[0],
+				Pounds: pounds,
+				Span:   p.spanFrom(start.Pos),
+			}, nil
+		case pounds > 0:
+			return &ast.RawStringExpression{
+				Value:  texts[0],
+				Pounds: pounds,
+				Span:   p.spanFrom(start.Pos),
+			}, nil
+		}
+		return ast.StringExpression(texts[0]), nil
+	}
+
+	var parts ast.StringParts
+	for i, text := range texts {
+		if text != "" {
+			parts = append(parts, ast.StringText(text))
+		}
+		if i < len(interpolations) {
+			parts = append(parts, interpolations[i])
+		}
+	}
+
+	return &ast.InterpolatedStringExpression{
+		Parts:     parts,
+		Pounds:    pounds,
+		MultiLine: multiline,
+		Span:      p.spanFrom(start.Pos),
+	}, nil
+}
+
+func (p *parser) parseParameter() (*ast.Parameter, error) {
+	start := p.tok.Pos
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	param := &ast.Parameter{Name: name}
+
+	if p.got(token.COLON) {
+		param.Type, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	param.Span = p.spanFrom(start)
+
+	return param, nil
+}
+
+// parseParameterList parses comma-separated parameters until the closing
+// token, which is not consumed.
+func (p *parser) parseParameterList(closing token.Kind) (ast.Parameters, error) {
+	var params ast.Parameters
+
+	for p.tok.Kind != closing {
+		param, err := p.parseParameter()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+		if !p.got(token.COMMA) {
+			break
+		}
+	}
+
+	return params, nil
+}
+
+func (p *parser) parseTypeParameters() (ast.TypeParameters, error) {
+	if _, err := p.expect(token.LSS); err != nil {
+		return nil, err
+	}
+
+	var params ast.TypeParameters
+
+	for {
+		start := p.tok.Pos
+		param := &ast.TypeParameter{}
+
+		if p.tok.Kind == token.IN || p.tok.Kind == token.OUT {
+			param.Variance = ast.TypeVariance(p.tok.Text)
+			p.next()
+		}
+
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		param.Name = name
+		param.Span = p.spanFrom(start)
+		params = append(params, param)
+
+		if !p.got(token.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.expect(token.GTR); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// ----------------------------------------------------------------------------
+// Types
+
+func (p *parser) parseType() (ast.Type, error) {
+	start := p.tok.Pos
+
+	var members []ast.Type
+	var dflt ast.Type
+	// Number of members after the default.
+	after := 0
+	union := false
+
+	for {
+		isDefault := p.got(token.MUL)
+
+		typ, err := p.parsePostfixType()
+		if err != nil {
+			return nil, err
+		}
+
+		if isDefault {
+			if dflt != nil {
+				return nil, p.errorf(p.tok.Pos, "union type can only have one default")
+			}
+			dflt = typ
+		} else {
+			members = append(members, typ)
+			if dflt != nil {
+				after++
+			}
+		}
+
+		if p.tok.Kind != token.OR {
+			break
+		}
+		union = true
+		p.next()
+	}
+
+	if !union && dflt == nil {
+		return members[0], nil
+	}
+
+	return &ast.UnionType{Members: members, Default: dflt, MembersAfterDefault: after, Span: p.spanFrom(start)}, nil
+}
+
+func (p *parser) parsePostfixType() (ast.Type, error) {
+	start := p.tok.Pos
+
+	typ, err := p.parsePrimaryType()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.tok.Kind == token.QUESTION:
+			p.next()
+			typ = &ast.NullableType{Type: typ, Span: p.spanFrom(start)}
+		case p.tok.Kind == token.LPAREN && !p.tok.Newline:
+			p.next()
+			constraints, err := p.parseExpressionList(token.RPAREN)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(token.RPAREN); err != nil {
+				return nil, err
+			}
+			typ = &ast.ConstrainedType{
+				Type:        typ,
+				Constraints: constraints,
+				Span:        p.spanFrom(start),
+			}
+		default:
+			return typ, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimaryType() (ast.Type, error) {
+	start := p.tok.Pos
+
+	switch p.tok.Kind {
+	case token.UNKNOWN, token.NOTHING, token.MODULE:
+		typ := ast.BuiltinType(p.tok.Text)
+		p.next()
+		return typ, nil
+
+	case token.STRING_START:
+		s, err := p.parseStringConstant()
+		if err != nil {
+			return nil, err
+		}
+		return ast.StringLiteralType(s), nil
+
+	case token.IDENT:
+		name, err := p.parseQualifiedIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		typ := &ast.DeclaredType{Name: name}
+		if p.tok.Kind == token.LSS {
+			typ.TypeParameters, err = p.parseTypeArguments()
+			if err != nil {
+				return nil, err
+			}
+		}
+		typ.Span = p.spanFrom(start)
+		return typ, nil
+
+	case token.LPAREN:
+		p.next()
+
+		var types []ast.Type
+		for p.tok.Kind != token.RPAREN {
+			typ, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, typ)
+			if !p.got(token.COMMA) {
+				break
+			}
+		}
+		if _, err := p.expect(token.RPAREN); err != nil {
+			return nil, err
+		}
+
+		if p.got(token.ARROW) {
+			result, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			return &ast.FunctionLiteralType{
+				Parameters: types,
+				Result:     result,
+				Span:       p.spanFrom(start),
+			}, nil
+		}
+
+		if len(types) != 1 {
+			return nil, p.errorf(start, "expected a single type in parentheses")
+		}
+		return &ast.ParenthesizedType{Type: types[0], Span: p.spanFrom(start)}, nil
+	}
+
+	return nil, p.unexpected("type")
+}
+
+func (p *parser) parseTypeArguments() ([]ast.Type, error) {
+	if _, err := p.expect(token.LSS); err != nil {
+		return nil, err
+	}
+
+	var args []ast.Type
+	for {
+		typ, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, typ)
+
+		if !p.got(token.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.expect(token.GTR); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+// Binary operator precedences, from lowest to highest.
+// binaryOperators are the binary operators by token. Their precedences are
+// those ast uses to parenthesize operands when printing.
+var binaryOperators = map[token.Kind]ast.BinaryOperator{
+	token.COALESCE: ast.BinaryOperatorNullCoalesce,
+	token.PIPE:     ast.BinaryOperatorPipe,
+	token.LOR:      ast.BinaryOperatorLogicalOr,
+	token.LAND:     ast.BinaryOperatorLogicalAnd,
+	token.OR:       ast.BinaryOperatorBitwiseOr,
+	token.AND:      ast.BinaryOperatorBitwiseAnd,
+	token.EQL:      ast.BinaryOperatorEqual,
+	token.NEQ:      ast.BinaryOperatorNotEqual,
+	token.LSS:      ast.BinaryOperatorLessThan,
+	token.LEQ:      ast.BinaryOperatorLessThanOrEqual,
+	token.GTR:      ast.BinaryOperatorGreaterThan,
+	token.GEQ:      ast.BinaryOperatorGreaterThanOrEqual,
+	token.ADD:      ast.BinaryOperatorPlus,
+	token.SUB:      ast.BinaryOperatorMinus,
+	token.MUL:      ast.BinaryOperatorMultiply,
+	token.QUO:      ast.BinaryOperatorDivide,
+	token.INT_DIV:  ast.BinaryOperatorIntegerDivide,
+	token.REM:      ast.BinaryOperatorModulo,
+	token.POW:      ast.BinaryOperatorExponent,
+}
+
+func (p *parser) parseExpression() (ast.Expression, error) {
+	return p.parseBinaryExpression(0)
+}
+
+// parseBinaryExpression parses a binary expression whose operators have at
+// least the given precedence.
+func (p *parser) parseBinaryExpression(minPrec int) (ast.Expression, error) {
+	start := p.tok.Pos
+
+	left, err := p.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.tok.Kind == token.IS || p.tok.Kind == token.AS {
+			op := ast.TypeOperator(p.tok.Text)
+			if op.Precedence() < minPrec {
+				return left, nil
+			}
+			p.next()
+			typ, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			left = &ast.TypeExpression{
+				Operator:   op,
+				Expression: left,
+				Type:       typ,
+				Span:       p.spanFrom(start),
+			}
+			continue
+		}
+
+		op, ok := binaryOperators[p.tok.Kind]
+		if !ok || op.Precedence() < minPrec {
+			return left, nil
+		}
+		// A minus on a new line starts a new object element instead.
+		if p.tok.Kind == token.SUB && p.tok.Newline {
+			return left, nil
+		}
+		p.next()
+
+		_, rightPrec := op.OperandPrecedences()
+		right, err := p.parseBinaryExpression(rightPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &ast.BinaryExpression{
+			Operator: op,
+			Left:     left,
+			Right:    right,
+			Span:     p.spanFrom(start),
+		}
+	}
+}
+
+func (p *parser) parseUnaryExpression() (ast.Expression, error) {
+	start := p.tok.Pos
+
+	var op ast.PrefixUnaryOperand
+	switch p.tok.Kind {
+	case token.SUB:
+		op = ast.UnaryOperandMinus
+	case token.NOT:
+		op = ast.UnaryOperandLogicalNot
+	default:
+		return p.parsePostfixExpression()
+	}
+	p.next()
+
+	// A minus before an integer literal is part of the literal, so that the
+	// lowest integer, which has no positive counterpart, can be read back.
+	// Postfix operations bind tighter than the minus, though.
+	if op == ast.UnaryOperandMinus && p.tok.Kind == token.INT && !startsPostfix(p.peek(1)) {
+		tok := p.tok
+		p.next()
+		v, err := parseInt("-" + tok.Text)
+		if err != nil {
+			return nil, p.errorf(start, "invalid integer literal -%s", tok.Text)
+		}
+		return ast.IntExpression(v), nil
+	}
+
+	operand, err := p.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.PrefixUnaryExpression{
+		Operator: op,
+		Operand:  operand,
+		Span:     p.spanFrom(start),
+	}, nil
+}
+
+// startsPostfix reports whether tok starts a postfix operation: a member
+// access, a subscript or a non-null assertion.
+func startsPostfix(tok token.Token) bool {
+	switch tok.Kind {
+	case token.DOT, token.QDOT, token.NON_NULL:
+		return true
+	case token.LBRACK:
+		return !tok.Newline
+	}
+	return false
+}
+
+func (p *parser) parsePostfixExpression() (ast.Expression, error) {
+	start := p.tok.Pos
+
+	expr, err := p.parsePrimaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.tok.Kind == token.DOT || p.tok.Kind == token.QDOT:
+			nullable := p.tok.Kind == token.QDOT
+			p.next()
+			name, err := p.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			args, err := p.parseOptionalArguments()
+			if err != nil {
+				return nil, err
+			}
+			expr = &ast.QualifiedMemberAccessExpression{
+				Receiver:  expr,
+				Nullable:  nullable,
+				Name:      name,
+				Arguments: args,
+				Span:      p.spanFrom(start),
+			}
+
+		case p.tok.Kind == token.LBRACK && !p.tok.Newline:
+			p.next()
+			subscript, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(token.RBRACK); err != nil {
+				return nil, err
+			}
+			expr = &ast.SubscriptExpression{
+				Receiver:  expr,
+				Subscript: subscript,
+				Span:      p.spanFrom(start),
+			}
+
+		case p.tok.Kind == token.NON_NULL:
+			p.next()
+			expr = &ast.PostfixUnaryExpression{
+				Operator: ast.PostfixUnaryOperandNonNullAssertion,
+				Operand:  expr,
+				Span:     p.spanFrom(start),
+			}
+
+		case p.tok.Kind == token.LBRACE:
+			parent, ok := expr.(ast.AmendParentExpression)
+			if !ok {
+				return expr, nil
+			}
+			body, err := p.parseObjectBody()
+			if err != nil {
+				return nil, err
+			}
+			expr = &ast.AmendExpression{
+				Parent: parent,
+				Body:   body,
+				Span:   p.spanFrom(start),
+			}
+
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// parseOptionalArguments parses an argument list if one starts on the same
+// line. It returns nil when there is none.
+func (p *parser) parseOptionalArguments() (ast.Expressions, error) {
+	if p.tok.Kind != token.LPAREN || p.tok.Newline {
+		return nil, nil
+	}
+	p.next()
+
+	args, err := p.parseExpressionList(token.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.RPAREN); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = ast.Expressions{}
+	}
+
+	return args, nil
+}
+
+// parseExpressionList parses comma-separated expressions until the closing
+// token, which is not consumed.
+func (p *parser) parseExpressionList(closing token.Kind) (ast.Expressions, error) {
+	var exprs ast.Expressions
+
+	for p.tok.Kind != closing {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if !p.got(token.COMMA) {
+			break
+		}
+	}
+
+	return exprs, nil
+}
+
+// tryParseFunctionParameters parses the parameters of a function literal
+// ("(a, b) -> ..."). It backtracks and reports false if the parentheses do not
+// start a function literal.
+func (p *parser) tryParseFunctionParameters() (ast.Parameters, bool) {
+	saved := p.save()
+
+	p.next()
+	params, err := p.parseParameterList(token.RPAREN)
+	if err != nil || !p.got(token.RPAREN) || !p.got(token.ARROW) {
+		p.restore(saved)
+		return nil, false
+	}
+
+	return params, true
+}
+
+// parseParenthesized parses an expression between parentheses.
+func (p *parser) parseParenthesized() (ast.Expression, error) {
+	if _, err := p.expect(token.LPAREN); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.RPAREN); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (p *parser) parsePrimaryExpression() (ast.Expression, error) {
+	tok := p.tok
+	start := tok.Pos
+
+	switch tok.Kind {
+	case token.THIS, token.OUTER, token.MODULE, token.NULL, token.TRUE, token.FALSE:
+		p.next()
+		return ast.BuiltinExpression(tok.Text), nil
+
+	case token.INT:
+		p.next()
+		v, err := parseInt(tok.Text)
+		if err != nil {
+			return nil, p.errorf(tok.Pos, "invalid integer literal %s", tok.Text)
+		}
+		return ast.IntExpression(v), nil
+
+	case token.FLOAT:
+		p.next()
+		v, err := parseFloat(tok.Text)
+		if err != nil {
+			return nil, p.errorf(tok.Pos, "invalid float literal %s", tok.Text)
+		}
+		return ast.FloatExpression(v), nil
+
+	case token.STRING_START:
+		return p.parseStringLiteral()
+
+	case token.IDENT:
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseOptionalArguments()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.MemberAccessExpression{
+			Name:      name,
+			Arguments: args,
+			Span:      p.spanFrom(start),
+		}, nil
+
+	case token.SUPER:
+		p.next()
+		if p.got(token.LBRACK) {
+			subscript, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(token.RBRACK); err != nil {
+				return nil, err
+			}
+			return &ast.SuperSubscriptExpression{
+				Subscript: subscript,
+				Span:      p.spanFrom(start),
+			}, nil
+		}
+		if _, err := p.expect(token.DOT); err != nil {
+			return nil, err
+		}
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseOptionalArguments()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.SuperAccessExpression{
+			Name:      name,
+			Arguments: args,
+			Span:      p.spanFrom(start),
+		}, nil
+
+	case token.LPAREN:
+		if params, ok := p.tryParseFunctionParameters(); ok {
+			body, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return &ast.FunctionLiteralExpression{
+				Parameters: params,
+				Body:       body,
+				Span:       p.spanFrom(start),
+			}, nil
+		}
+		expr, err := p.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ParenthesizedExpression{
+			Expression: expr,
+			Span:       p.spanFrom(start),
+		}, nil
+
+	case token.NEW:
+		p.next()
+		expr := &ast.NewExpression{}
+		if p.tok.Kind != token.LBRACE {
+			typ, err := p.parsePrimaryType()
+			if err != nil {
+				return nil, err
+			}
+			expr.Type = typ
+		}
+		body, err := p.parseObjectBody()
+		if err != nil {
+			return nil, err
+		}
+		expr.Body = body
+		expr.Span = p.spanFrom(start)
+		return expr, nil
+
+	case token.IF:
+		p.next()
+		cond, err := p.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		then, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(token.ELSE); err != nil {
+			return nil, err
+		}
+		elseExpr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.IfExpression{
+			Condition: cond,
+			Then:      then,
+			Else:      elseExpr,
+			Span:      p.spanFrom(start),
+		}, nil
+
+	case token.LET:
+		p.next()
+		if _, err := p.expect(token.LPAREN); err != nil {
+			return nil, err
+		}
+		name, err := p.parseParameter()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(token.ASSIGN); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(token.RPAREN); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LetExpression{
+			Name:       name,
+			Value:      value,
+			Expression: expr,
+			Span:       p.spanFrom(start),
+		}, nil
+
+	case token.IMPORT, token.IMPORT_GLOB:
+		p.next()
+		if _, err := p.expect(token.LPAREN); err != nil {
+			return nil, err
+		}
+		path, err := p.parseStringConstant()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(token.RPAREN); err != nil {
+			return nil, err
+		}
+		return &ast.ImportExpression{
+			Path: path,
+			Glob: tok.Kind == token.IMPORT_GLOB,
+			Span: p.spanFrom(start),
+		}, nil
+
+	case token.READ, token.READ_NULL, token.READ_GLOB:
+		p.next()
+		value, err := p.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		expr := &ast.ReadExpression{Value: value, Span: p.spanFrom(start)}
+		switch tok.Kind {
+		case token.READ_NULL:
+			expr.Variant = ast.ReadVariantNullable
+		case token.READ_GLOB:
+			expr.Variant = ast.ReadVariantGlob
+		}
+		return expr, nil
+
+	case token.THROW:
+		p.next()
+		value, err := p.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ThrowExpression{Value: value, Span: p.spanFrom(start)}, nil
+
+	case token.TRACE:
+		p.next()
+		value, err := p.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TraceExpression{Value: value, Span: p.spanFrom(start)}, nil
+	}
+
+	return nil, p.unexpected("expression")
+}
+
+// ----------------------------------------------------------------------------
+// Objects
+
+func (p *parser) parseObjectBody() (*ast.ObjectBody, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.LBRACE); err != nil {
+		return nil, err
+	}
+
+	body := &ast.ObjectBody{}
+
+	params, ok := p.tryParseObjectParameters()
+	if ok {
+		body.Parameters = params
+	}
+
+	p.dropComments()
+	for {
+		for p.got(token.SEMICOLON) {
+		}
+		if p.tok.Kind == token.RBRACE || p.tok.Kind == token.EOF {
+			break
+		}
+
+		from := p.pos
+		standalone, leading := p.memberComments(p.tok.Pos)
+		body.Members = appendComments(body.Members, standalone)
+
+		member, err := p.parseObjectMember()
+		if err != nil {
+			if err := p.recoverMember(err, from, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		setComments(member, leading, p.trailingComment())
+		body.Members = append(body.Members, member)
+	}
+	body.Members = appendComments(body.Members, p.standaloneComments())
+
+	if _, err := p.expect(token.RBRACE); err != nil {
+		return nil, err
+	}
+
+	body.Span = p.spanFrom(start)
+
+	return body, nil
+}
+
+// tryParseObjectParameters parses the parameters of an object body
+// ("{ a, b -> ..."). It backtracks and reports false if the body does not
+// start with parameters.
+func (p *parser) tryParseObjectParameters() (ast.Parameters, bool) {
+	if p.tok.Kind != token.IDENT {
+		return nil, false
+	}
+
+	saved := p.save()
+
+	params, err := p.parseParameterList(token.ARROW)
+	if err != nil || !p.got(token.ARROW) {
+		p.restore(saved)
+		return nil, false
+	}
+
+	return params, true
+}
+
+func (p *parser) parseObjectMember() (ast.ObjectMember, error) {
+	start := p.tok.Pos
+
+	if modifiers := p.parseModifiers(); len(modifiers) > 0 {
+		if p.tok.Kind == token.FUNCTION {
+			return p.parseObjectMethod(start, modifiers)
+		}
+		return p.parseObjectProperty(start, modifiers)
+	}
+
+	switch p.tok.Kind {
+	case token.FUNCTION:
+		return p.parseObjectMethod(start, nil)
+
+	case token.IDENT:
+		switch p.peek(1).Kind {
+		case token.ASSIGN, token.COLON, token.LBRACE:
+			return p.parseObjectProperty(start, nil)
+		}
+
+	case token.LPRED:
+		return p.parseMemberPredicate()
+
+	case token.LBRACK:
+		return p.parseObjectEntry()
+
+	case token.SPREAD, token.QSPREAD:
+		spread := &ast.ObjectSpread{Nullable: p.tok.Kind == token.QSPREAD}
+		p.next()
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		spread.Value = value
+		spread.Span = p.spanFrom(start)
+		return spread, nil
+
+	case token.WHEN:
+		return p.parseWhenGenerator()
+
+	case token.FOR:
+		return p.parseForGenerator()
+	}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ObjectElement{Value: value, Span: p.spanFrom(start)}, nil
+}
+
+func (p *parser) parseObjectProperty(start token.Pos, modifiers ast.Modifiers) (*ast.ObjectProperty, error) {
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	prop := &ast.ObjectProperty{Modifiers: modifiers, Name: name}
+
+	if p.got(token.COLON) {
+		prop.Type, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Kind != token.ASSIGN {
+			return nil, p.unexpected("'='")
+		}
+	}
+
+	prop.Value, prop.Body, err = p.parseObjectMemberValue()
+	if err != nil {
+		return nil, err
+	}
+
+	prop.Span = p.spanFrom(start)
+
+	return prop, nil
+}
+
+// parseObjectMemberValue parses either "= value" or one or more object
+// bodies.
+func (p *parser) parseObjectMemberValue() (ast.Expression, []*ast.ObjectBody, error) {
+	if p.got(token.ASSIGN) {
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, nil, err
+		}
+		return value, nil, nil
+	}
+
+	if p.tok.Kind != token.LBRACE {
+		return nil, nil, p.unexpected("'=' or '{'")
+	}
+
+	var bodies []*ast.ObjectBody
+	for p.tok.Kind == token.LBRACE {
+		body, err := p.parseObjectBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		bodies = append(bodies, body)
+	}
+
+	return nil, bodies, nil
+}
+
+func (p *parser) parseObjectMethod(start token.Pos, modifiers ast.Modifiers) (*ast.ObjectMethod, error) {
+	sig, err := p.parseMethodSignature(start, modifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(token.ASSIGN); err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ObjectMethod{Signature: sig, Value: value, Span: p.spanFrom(start)}, nil
+}
+
+func (p *parser) parseMemberPredicate() (*ast.MemberPredicate, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.LPRED); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for range 2 {
+		if _, err := p.expect(token.RBRACK); err != nil {
+			return nil, err
+		}
+	}
+
+	pred := &ast.MemberPredicate{Condition: cond}
+	pred.Value, pred.Body, err = p.parseObjectMemberValue()
+	if err != nil {
+		return nil, err
+	}
+
+	pred.Span = p.spanFrom(start)
+
+	return pred, nil
+}
+
+func (p *parser) parseObjectEntry() (*ast.ObjectEntry, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.LBRACK); err != nil {
+		return nil, err
+	}
+
+	key, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(token.RBRACK); err != nil {
+		return nil, err
+	}
+
+	entry := &ast.ObjectEntry{Key: key}
+	entry.Value, entry.Body, err = p.parseObjectMemberValue()
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Span = p.spanFrom(start)
+
+	return entry, nil
+}
+
+func (p *parser) parseWhenGenerator() (*ast.WhenGenerator, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.WHEN); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseParenthesized()
+	if err != nil {
+		return nil, err
+	}
+
+	gen := &ast.WhenGenerator{Condition: cond}
+
+	gen.Then, err = p.parseObjectBody()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.got(token.ELSE) {
+		gen.Else, err = p.parseObjectBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gen.Span = p.spanFrom(start)
+
+	return gen, nil
+}
+
+func (p *parser) parseForGenerator() (*ast.ForGenerator, error) {
+	start := p.tok.Pos
+	if _, err := p.expect(token.FOR); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.LPAREN); err != nil {
+		return nil, err
+	}
+
+	gen := &ast.ForGenerator{}
+
+	value, err := p.parseParameter()
+	if err != nil {
+		return nil, err
+	}
+	if p.got(token.COMMA) {
+		gen.Key = value
+		value, err = p.parseParameter()
+		if err != nil {
+			return nil, err
+		}
+	}
+	gen.Value = value
+
+	if _, err := p.expect(token.IN); err != nil {
+		return nil, err
+	}
+
+	gen.Collection, err = p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(token.RPAREN); err != nil {
+		return nil, err
+	}
+
+	gen.Body, err = p.parseObjectBody()
+	if err != nil {
+		return nil, err
+	}
+
+	gen.Span = p.spanFrom(start)
+
+	return gen, nil
+}
@@ -0,0 +1,1512 @@
+package parser
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModule(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		res  *ast.Module
+		err  error
+	}{
+		{
+			name: "empty",
+			src:  "",
+			res:  &ast.Module{},
+		},
+		{
+			name: "header",
+			src: stringsutil.StripMargin(`
+				|#!/usr/bin/env pkl eval
+				|/// Module docs.
+				|@Deprecated
+				|open module foo.bar
+				|
+				|extends "base.pkl"
+			`),
+			res: &ast.Module{
+				ShebangComment:     "/usr/bin/env pkl eval",
+				Docs:               "Module docs.",
+				Annotations:        ast.Annotations{&ast.Annotation{Name: "Deprecated"}},
+				Modifiers:          ast.Modifiers{ast.ModifierOpen},
+				Name:               "foo.bar",
+				ParentRelationship: ast.ModuleRelationshipExtends,
+				ParentName:         "base.pkl",
+			},
+		},
+		{
+			name: "imports",
+			src: stringsutil.StripMargin(`
+				|amends "pkl:Project"
+				|
+				|import "@foo/Bar.pkl"
+				|import* "*.pkl" as all
+			`),
+			res: &ast.Module{
+				ParentRelationship: ast.ModuleRelationshipAmends,
+				ParentName:         "pkl:Project",
+				Imports: ast.ImportClauses{
+					&ast.ImportClause{Path: "@foo/Bar.pkl"},
+					&ast.ImportClause{Path: "*.pkl", Alias: "all", Glob: true},
+				},
+			},
+		},
+		{
+			name: "docs belong to the first member without header",
+			src: stringsutil.StripMargin(`
+				|/// The port.
+				|port: Int = 8080
+			`),
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.ClassProperty{
+						Docs:       "The port.",
+						Name:       "port",
+						Type:       &ast.DeclaredType{Name: "Int"},
+						Expression: ast.IntExpression(8080),
+					},
+				},
+			},
+		},
+		{
+			name: "annotated member after imports without header",
+			src: stringsutil.StripMargin(`
+				|import "pkl:json"
+				|
+				|@Deprecated
+				|port = 8080
+			`),
+			res: &ast.Module{
+				Imports: ast.ImportClauses{
+					&ast.ImportClause{Path: "pkl:json"},
+				},
+				Members: ast.ModuleMembers{
+					&ast.ClassProperty{
+						Annotations: ast.Annotations{&ast.Annotation{Name: "Deprecated"}},
+						Name:        "port",
+						Expression:  ast.IntExpression(8080),
+					},
+				},
+			},
+		},
+		{
+			name: "class",
+			src: stringsutil.StripMargin(`
+				|abstract class Foo<in T> extends Bar<T> {
+				|  /// Docs.
+				|  hidden bar: String(!isEmpty)?
+				|  function baz(x: T): Int = 42
+				|}
+			`),
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.Class{
+						Modifiers: ast.Modifiers{ast.ModifierAbstract},
+						Name:      "Foo",
+						TypeParameters: ast.TypeParameters{
+							&ast.TypeParameter{Variance: ast.VarianceIn, Name: "T"},
+						},
+						ParentName: "Bar",
+						ParentTypeParameters: ast.TypeParameters{
+							&ast.TypeParameter{Name: "T"},
+						},
+						Members: []ast.ClassMember{
+							&ast.ClassProperty{
+								Docs:      "Docs.",
+								Modifiers: ast.Modifiers{ast.ModifierHidden},
+								Name:      "bar",
+								Type: &ast.NullableType{
+									Type: &ast.ConstrainedType{
+										Type: &ast.DeclaredType{Name: "String"},
+										Constraints: ast.Expressions{
+											&ast.PrefixUnaryExpression{
+												Operator: ast.UnaryOperandLogicalNot,
+												Operand:  &ast.MemberAccessExpression{Name: "isEmpty"},
+											},
+										},
+									},
+								},
+							},
+							&ast.ClassMethod{
+								Signature: &ast.MethodSignature{
+									Name: "baz",
+									Parameters: ast.Parameters{
+										&ast.Parameter{Name: "x", Type: &ast.DeclaredType{Name: "T"}},
+									},
+									Result: &ast.DeclaredType{Name: "Int"},
+								},
+								Implementation: ast.IntExpression(42),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "type alias",
+			src:  `typealias Mode = "a" | *"b" | "c"`,
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.TypeAlias{
+						Name: "Mode",
+						Type: &ast.UnionType{
+							Members: []ast.Type{
+								ast.StringLiteralType("a"),
+								ast.StringLiteralType("c"),
+							},
+							Default:             ast.StringLiteralType("b"),
+							MembersAfterDefault: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "object members",
+			src: stringsutil.StripMargin(`
+				|foo {
+				|  bar = 1
+				|  ["baz"] = 2.5
+				|  "qux"
+				|  ...others
+				|  [[isEmpty]] { x = true }
+				|  local function double(n: Int) = n * 2
+				|}
+			`),
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.ClassProperty{
+						Name: "foo",
+						Body: &ast.ObjectBody{
+							Members: ast.ObjectMembers{
+								&ast.ObjectProperty{Name: "bar", Value: ast.IntExpression(1)},
+								&ast.ObjectEntry{Key: ast.StringExpression("baz"), Value: ast.FloatExpression(2.5)},
+								&ast.ObjectElement{Value: ast.StringExpression("qux")},
+								&ast.ObjectSpread{Value: &ast.MemberAccessExpression{Name: "others"}},
+								&ast.MemberPredicate{
+									Condition: &ast.MemberAccessExpression{Name: "isEmpty"},
+									Body: []*ast.ObjectBody{
+										{
+											Members: ast.ObjectMembers{
+												&ast.ObjectProperty{Name: "x", Value: ast.ExpressionTrue},
+											},
+										},
+									},
+								},
+								&ast.ObjectMethod{
+									Signature: &ast.MethodSignature{
+										Modifiers: ast.Modifiers{ast.ModifierLocal},
+										Name:      "double",
+										Parameters: ast.Parameters{
+											&ast.Parameter{Name: "n", Type: &ast.DeclaredType{Name: "Int"}},
+										},
+									},
+									Value: &ast.BinaryExpression{
+										Operator: ast.BinaryOperatorMultiply,
+										Left:     &ast.MemberAccessExpression{Name: "n"},
+										Right:    ast.IntExpression(2),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "elements on separate lines",
+			src: stringsutil.StripMargin(`
+				|foo {
+				|  1
+				|  -1
+				|  (bar)
+				|}
+			`),
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.ClassProperty{
+						Name: "foo",
+						Body: &ast.ObjectBody{
+							Members: ast.ObjectMembers{
+								&ast.ObjectElement{Value: ast.IntExpression(1)},
+								&ast.ObjectElement{Value: ast.IntExpression(-1)},
+								&ast.ObjectElement{
+									Value: &ast.ParenthesizedExpression{
+										Expression: &ast.MemberAccessExpression{Name: "bar"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "object body parameters",
+			src:  `foo = new Mapping { k, v -> }`,
+			res: &ast.Module{
+				Members: ast.ModuleMembers{
+					&ast.ClassProperty{
+						Name: "foo",
+						Expression: &ast.NewExpression{
+							Type: &ast.DeclaredType{Name: "Mapping"},
+							Body: &ast.ObjectBody{
+								Parameters: ast.Parameters{
+									&ast.Parameter{Name: "k"},
+									&ast.Parameter{Name: "v"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unterminated string",
+			src:  `foo = "bar`,
+			err:  &Error{Line: 1, Column: 7, Msg: "string literal not terminated"},
+		},
+		{
+			name: "unexpected token",
+			src:  `foo = 1 +`,
+			err:  &Error{Line: 1, Column: 10, Msg: "expected expression, found end of file"},
+		},
+		{
+			name: "missing value",
+			src: stringsutil.StripMargin(`
+				|foo {
+				|  bar: Int
+				|}
+			`),
+			err: &Error{Line: 3, Column: 1, Msg: "expected '=', found '}'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := ParseModule([]byte(test.src))
+			clearSpans(res)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		res  ast.Expression
+		err  error
+	}{
+		{
+			name: "literal",
+			src:  "42",
+			res:  ast.IntExpression(42),
+		},
+		{
+			name: "lowest integer",
+			src:  "-9223372036854775808",
+			res:  ast.IntExpression(math.MinInt64),
+		},
+		{
+			name: "negative hexadecimal integer",
+			src:  "-0x10",
+			res:  ast.IntExpression(-16),
+		},
+		{
+			name: "negated member of integer",
+			src:  "-5.abs",
+			res: &ast.PrefixUnaryExpression{
+				Operator: ast.UnaryOperandMinus,
+				Operand: &ast.QualifiedMemberAccessExpression{
+					Receiver: ast.IntExpression(5),
+					Name:     "abs",
+				},
+			},
+		},
+		{
+			name: "integer out of range",
+			src:  "-9223372036854775809",
+			err:  &Error{Line: 1, Column: 1, Msg: "invalid integer literal -9223372036854775809"},
+		},
+		{
+			name: "call with null coalescing",
+			src:  "foo.bar(1) ?? 2",
+			res: &ast.BinaryExpression{
+				Operator: ast.BinaryOperatorNullCoalesce,
+				Left: &ast.QualifiedMemberAccessExpression{
+					Receiver:  &ast.MemberAccessExpression{Name: "foo"},
+					Name:      "bar",
+					Arguments: ast.Expressions{ast.IntExpression(1)},
+				},
+				Right: ast.IntExpression(2),
+			},
+		},
+		{
+			name: "custom delimiters",
+			src:  `#"C:\path"#`,
+			res:  &ast.RawStringExpression{Value: `C:\path`, Pounds: 1},
+		},
+		{
+			name: "custom delimiters escape",
+			src:  `##"a"#\##tb"##`,
+			res:  &ast.RawStringExpression{Value: "a\"#\tb", Pounds: 2},
+		},
+		{
+			name: "multi-line string",
+			src:  "\"\"\"\n  foo\n    \"bar\"\n  \"\"\"",
+			res:  &ast.MultiLineStringExpression{Value: "foo\n  \"bar\""},
+		},
+		{
+			name: "multi-line string custom delimiters",
+			src:  "#\"\"\"\n  C:\\path\n  \"\"\"#",
+			res:  &ast.MultiLineStringExpression{Value: `C:\path`, Pounds: 1},
+		},
+		{
+			name: "interpolation",
+			src:  `"Hello, \(name)!"`,
+			res: &ast.InterpolatedStringExpression{
+				Parts: ast.StringParts{
+					ast.StringText("Hello, "),
+					&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{Name: "name"}},
+					ast.StringText("!"),
+				},
+			},
+		},
+		{
+			name: "nested interpolation",
+			src:  `"\(f("\(x)"))"`,
+			res: &ast.InterpolatedStringExpression{
+				Parts: ast.StringParts{
+					&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{
+						Name: "f",
+						Arguments: ast.Expressions{
+							&ast.InterpolatedStringExpression{
+								Parts: ast.StringParts{
+									&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{Name: "x"}},
+								},
+							},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name: "interpolation custom delimiters",
+			src:  `#"\(a) \#(b)"#`,
+			res: &ast.InterpolatedStringExpression{
+				Parts: ast.StringParts{
+					ast.StringText(`\(a) `),
+					&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{Name: "b"}},
+				},
+				Pounds: 1,
+			},
+		},
+		{
+			name: "multi-line interpolation",
+			src:  "\"\"\"\n  a \\(b)\n  \\(c) d\n  \"\"\"",
+			res: &ast.InterpolatedStringExpression{
+				Parts: ast.StringParts{
+					ast.StringText("a "),
+					&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{Name: "b"}},
+					ast.StringText("\n"),
+					&ast.StringInterpolation{Expression: &ast.MemberAccessExpression{Name: "c"}},
+					ast.StringText(" d"),
+				},
+				MultiLine: true,
+			},
+		},
+		{
+			name: "multi-line interpolation not indented",
+			src:  "\"\"\"\n  a\n\\(b)\n  \"\"\"",
+			err:  &Error{Line: 1, Column: 1, Msg: "line 2 of multi-line string must be indented at least as much as the closing delimiter"},
+		},
+		{
+			name: "function literal",
+			src:  "(a: Int, b) -> a + b",
+			res: &ast.FunctionLiteralExpression{
+				Parameters: ast.Parameters{
+					&ast.Parameter{Name: "a", Type: &ast.DeclaredType{Name: "Int"}},
+					&ast.Parameter{Name: "b"},
+				},
+				Body: &ast.BinaryExpression{
+					Operator: ast.BinaryOperatorPlus,
+					Left:     &ast.MemberAccessExpression{Name: "a"},
+					Right:    &ast.MemberAccessExpression{Name: "b"},
+				},
+			},
+		},
+		{
+			name: "function literal without parameters",
+			src:  "() -> 42",
+			res:  &ast.FunctionLiteralExpression{Body: ast.IntExpression(42)},
+		},
+		{
+			name: "function literal argument",
+			src:  "list.map((it) -> it)",
+			res: &ast.QualifiedMemberAccessExpression{
+				Receiver: &ast.MemberAccessExpression{Name: "list"},
+				Name:     "map",
+				Arguments: ast.Expressions{
+					&ast.FunctionLiteralExpression{
+						Parameters: ast.Parameters{&ast.Parameter{Name: "it"}},
+						Body:       &ast.MemberAccessExpression{Name: "it"},
+					},
+				},
+			},
+		},
+		{
+			name: "parenthesized identifier",
+			src:  "(it)",
+			res:  &ast.ParenthesizedExpression{Expression: &ast.MemberAccessExpression{Name: "it"}},
+		},
+		{
+			name: "call without arguments",
+			src:  "foo()",
+			res: &ast.MemberAccessExpression{
+				Name:      "foo",
+				Arguments: ast.Expressions{},
+			},
+		},
+		{
+			name: "precedence",
+			src:  "a || b && !c",
+			res: &ast.BinaryExpression{
+				Operator: ast.BinaryOperatorLogicalOr,
+				Left:     &ast.MemberAccessExpression{Name: "a"},
+				Right: &ast.BinaryExpression{
+					Operator: ast.BinaryOperatorLogicalAnd,
+					Left:     &ast.MemberAccessExpression{Name: "b"},
+					Right: &ast.PrefixUnaryExpression{
+						Operator: ast.UnaryOperandLogicalNot,
+						Operand:  &ast.MemberAccessExpression{Name: "c"},
+					},
+				},
+			},
+		},
+		{
+			name: "left associativity",
+			src:  "a - b - c",
+			res: &ast.BinaryExpression{
+				Operator: ast.BinaryOperatorMinus,
+				Left: &ast.BinaryExpression{
+					Operator: ast.BinaryOperatorMinus,
+					Left:     &ast.MemberAccessExpression{Name: "a"},
+					Right:    &ast.MemberAccessExpression{Name: "b"},
+				},
+				Right: &ast.MemberAccessExpression{Name: "c"},
+			},
+		},
+		{
+			name: "right associativity",
+			src:  "a ?? b ?? c",
+			res: &ast.BinaryExpression{
+				Operator: ast.BinaryOperatorNullCoalesce,
+				Left:     &ast.MemberAccessExpression{Name: "a"},
+				Right: &ast.BinaryExpression{
+					Operator: ast.BinaryOperatorNullCoalesce,
+					Left:     &ast.MemberAccessExpression{Name: "b"},
+					Right:    &ast.MemberAccessExpression{Name: "c"},
+				},
+			},
+		},
+		{
+			name: "type test",
+			src:  "x as String?",
+			res: &ast.TypeExpression{
+				Operator:   ast.TypeOperatorAs,
+				Expression: &ast.MemberAccessExpression{Name: "x"},
+				Type:       &ast.NullableType{Type: &ast.DeclaredType{Name: "String"}},
+			},
+		},
+		{
+			name: "unexpected token",
+			src:  "foo.bar(1 ?? 2",
+			err:  &Error{Line: 1, Column: 15, Msg: "expected ')', found end of file"},
+		},
+		{
+			name: "trailing tokens",
+			src:  "foo bar",
+			err:  &Error{Line: 1, Column: 5, Msg: "expected end of expression, found 'bar'"},
+		},
+		{
+			name: "invalid character",
+			src:  "1 + ^",
+			err:  &Error{Line: 1, Column: 5, Msg: "invalid character '^'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := ParseExpression(test.src)
+			clearSpans(res)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestParseExpressionRoundTripStripParentheses(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		res  string
+	}{
+		{
+			name: "negated negation",
+			src:  "!(!a)",
+			res:  "!(!a)",
+		},
+		{
+			name: "negated non-null assertion",
+			src:  "!(a!!)",
+			res:  "!a!!",
+		},
+		{
+			name: "negated minus",
+			src:  "-(-a)",
+			res:  "--a",
+		},
+		{
+			name: "lowest integer",
+			src:  "-9223372036854775808",
+			res:  "-9223372036854775808",
+		},
+		{
+			name: "negated member of negative integer",
+			src:  "-(-5).abs",
+			res:  "-(-5).abs",
+		},
+		{
+			name: "rightmost if",
+			src:  "x + (if (c) a else b)",
+			res:  "x + if (c) a else b",
+		},
+		{
+			name: "rightmost function literal",
+			src:  "x |> ((a) -> a)",
+			res:  "x |> (a) -> a",
+		},
+		{
+			name: "negated let",
+			src:  "-(let (y = 1) y)",
+			res:  "-let (y = 1) y",
+		},
+		{
+			name: "if followed by operator",
+			src:  "(if (c) a else b) + y",
+			res:  "(if (c) a else b) + y",
+		},
+		{
+			name: "if in the middle of a chain",
+			src:  "(x * if (c) a else b) + y",
+			res:  "x * (if (c) a else b) + y",
+		},
+		{
+			name: "receiver ending with if",
+			src:  "(-if (c) a else b).foo",
+			res:  "(-if (c) a else b).foo",
+		},
+		{
+			name: "type test of let",
+			src:  "(x + let (y = 1) y) is Int",
+			res:  "(x + let (y = 1) y) is Int",
+		},
+	}
+
+	config := ast.DefaultPrintConfig
+	config.StripParentheses = true
+	ctx := ast.WithPrintConfig(context.Background(), config)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := ParseExpression(test.src)
+			assert.NoError(t, err)
+
+			res, err := expr.Marshal(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(res))
+
+			reparsed, err := ParseExpression(string(res))
+			assert.NoError(t, err)
+
+			res, err = reparsed.Marshal(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		res  ast.Type
+		err  error
+	}{
+		{
+			name: "builtin",
+			src:  "unknown",
+			res:  ast.TypeUnknown,
+		},
+		{
+			name: "constrained nullable listing",
+			src:  "Listing<String(!isEmpty)>?",
+			res: &ast.NullableType{
+				Type: &ast.DeclaredType{
+					Name: "Listing",
+					TypeParameters: []ast.Type{
+						&ast.ConstrainedType{
+							Type: &ast.DeclaredType{Name: "String"},
+							Constraints: ast.Expressions{
+								&ast.PrefixUnaryExpression{
+									Operator: ast.UnaryOperandLogicalNot,
+									Operand:  &ast.MemberAccessExpression{Name: "isEmpty"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "function",
+			src:  "(String, Int) -> Boolean",
+			res: &ast.FunctionLiteralType{
+				Parameters: []ast.Type{
+					&ast.DeclaredType{Name: "String"},
+					&ast.DeclaredType{Name: "Int"},
+				},
+				Result: &ast.DeclaredType{Name: "Boolean"},
+			},
+		},
+		{
+			name: "parenthesized union",
+			src:  `("a" | "b")?`,
+			res: &ast.NullableType{
+				Type: &ast.ParenthesizedType{
+					Type: &ast.UnionType{
+						Members: []ast.Type{
+							ast.StringLiteralType("a"),
+							ast.StringLiteralType("b"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "default first",
+			src:  `*"a" | "b" | "c"`,
+			res: &ast.UnionType{
+				Members: []ast.Type{
+					ast.StringLiteralType("b"),
+					ast.StringLiteralType("c"),
+				},
+				Default:             ast.StringLiteralType("a"),
+				MembersAfterDefault: 2,
+			},
+		},
+		{
+			name: "qualified",
+			src:  "base.Server",
+			res:  &ast.DeclaredType{Name: "base.Server"},
+		},
+		{
+			name: "unclosed type arguments",
+			src:  "Mapping<String, Int",
+			err:  &Error{Line: 1, Column: 20, Msg: "expected '>', found end of file"},
+		},
+		{
+			name: "missing type",
+			src:  "Listing<>",
+			err:  &Error{Line: 1, Column: 9, Msg: "expected type, found '>'"},
+		},
+		{
+			name: "trailing tokens",
+			src:  "String Int",
+			err:  &Error{Line: 1, Column: 8, Msg: "expected end of type, found 'Int'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := ParseType(test.src)
+			clearSpans(res)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestParseModuleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// Expected output when it differs from src.
+		res string
+	}{
+		{
+			name: "module",
+			src: stringsutil.StripMargin(`
+				|/// A module.
+				|@ModuleInfo {
+				|  minPklVersion = "0.25.0"
+				|}
+				|module foo.bar extends "./base.pkl"
+				|
+				|import "pkl:json"
+				|import* "*.pkl" as all
+				|
+				|local const max: Int = 10
+				|
+				|function inc(n: Int): Int = n + 1
+				|
+				|typealias Port = Int(isBetween(0, 65535))
+				|
+				|class Server {
+				|  host: String?
+				|
+				|  port: Port = 8080
+				|}
+				|
+			`),
+		},
+		{
+			name: "expressions",
+			src: stringsutil.StripMargin(`
+				|module exprs
+				|
+				|a = 1 + 2 * 3 - -4
+				|
+				|b = foo.bar(1, "x")?.baz ?? 2
+				|
+				|c = if (x is String) x.length else 0
+				|
+				|d = let (y: Int = 2) y ** 2 ** 3
+				|
+				|e = (base) {
+				|  name = "e"
+				|}
+				|
+				|f = read?("env:HOME") ?? throw("no home")
+				|
+				|g = import("foo.pkl").bar[0]!!
+				|
+				|h = super.foo + super["bar"]
+				|
+			`),
+		},
+		{
+			name: "union defaults",
+			src: stringsutil.StripMargin(`
+				|module unions
+				|
+				|typealias A = *"a" | "b" | "c"
+				|
+				|typealias B = "a" | *"b" | "c"
+				|
+				|typealias C = "a" | "b" | *"c"
+				|
+			`),
+		},
+		{
+			name: "generators",
+			src: stringsutil.StripMargin(`
+				|module generators
+				|
+				|res {
+				|  for (k, v in items) {
+				|    [k] = v
+				|  }
+				|  when (enabled) {
+				|    "on"
+				|  } else {
+				|    "off"
+				|  }
+				|}
+				|
+			`),
+		},
+		{
+			name: "normalized layout",
+			src: stringsutil.StripMargin(`
+				|module normalized
+				|x = new Listing<String> { "a"; "b" } // comment
+				|y = trace(0x1F + 0b101 + 1_000)
+				|z = """
+				|  multi
+				|    line
+				|  """
+			`),
+			res: stringsutil.StripMargin(`
+				|module normalized
+				|
+				|x = new Listing<String> {
+				|  "a"
+				|  "b"
+				|}
+				|
+				|y = trace(31 + 5 + 1000)
+				|
+				|z = """
+				|  multi
+				|    line
+				|  """
+				|
+			`),
+		},
+		{
+			name: "string escapes",
+			src: stringsutil.StripMargin(`
+				|module strings
+				|
+				|tab = "a\tb"
+				|control = "\u{0}\u{7F}"
+				|interpolation = "\\(foo)"
+				|unicode = "😀\u{200B}"
+				|
+			`),
+			res: stringsutil.StripMargin(`
+				|module strings
+				|
+				|tab = "a\tb"
+				|
+				|control = "\u{0}\u{7F}"
+				|
+				|interpolation = "\\(foo)"
+				|
+				|unicode = "😀\u{200B}"
+				|
+			`),
+		},
+		{
+			name: "quoted identifiers",
+			src: stringsutil.StripMargin(`
+				|module identifiers
+				|
+				|` + "`class` = 1" + `
+				|` + "`default-timeout` = `class` + 1" + `
+				|` + "`plain` = 2" + `
+				|
+			`),
+			res: stringsutil.StripMargin(`
+				|module identifiers
+				|
+				|` + "`class` = 1" + `
+				|
+				|` + "`default-timeout` = `class` + 1" + `
+				|
+				|plain = 2
+				|
+			`),
+		},
+		{
+			name: "floats",
+			src: stringsutil.StripMargin(`
+				|module floats
+				|
+				|one = 1.0
+				|large = 1e21
+				|small = 1.5e-07
+				|
+			`),
+			res: stringsutil.StripMargin(`
+				|module floats
+				|
+				|one = 1.0
+				|
+				|large = 1e+21
+				|
+				|small = 1.5e-07
+				|
+			`),
+		},
+		{
+			name: "interpolated strings",
+			src: stringsutil.StripMargin(`
+				|module strings
+				|
+				|greeting = "Hello, \(name)!"
+				|raw = #"C:\\#(dir)\file"#
+				|script = """
+				|  cd \(dir)
+				|
+				|  echo "\(1 + 2)"
+				|  """
+				|
+			`),
+			res: stringsutil.StripMargin(`
+				|module strings
+				|
+				|greeting = "Hello, \(name)!"
+				|
+				|raw = #"C:\\#(dir)\file"#
+				|
+				|script = """
+				|  cd \(dir)
+				|
+				|  echo "\(1 + 2)"
+				|  """
+				|
+			`),
+		},
+		{
+			name: "multi-line strings",
+			src: stringsutil.StripMargin(`
+				|module strings
+				|
+				|script {
+				|  run = """
+				|    echo "\\(HOME)"
+				|
+				|    say ""\""
+				|    """
+				|  raw = #"""
+				|    C:\path
+				|    """#
+				|}
+				|
+			`),
+			res: stringsutil.StripMargin(`
+				|module strings
+				|
+				|script {
+				|  run = """
+				|    echo "\\(HOME)"
+				|
+				|    say ""\""
+				|    """
+				|  raw = #"""
+				|    C:\path
+				|    """#
+				|}
+				|
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mod, err := ParseModule([]byte(test.src))
+			assert.NoError(t, err)
+			assert.NoError(t, mod.Validate())
+
+			res, err := mod.Marshal(context.Background())
+			assert.NoError(t, err)
+
+			expected := test.res
+			if expected == "" {
+				expected = test.src
+			}
+			assert.Equal(t, expected, string(res))
+		})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	src := stringsutil.StripMargin(`
+		|#! /usr/bin/env pkl
+		|// Copyright.
+		|
+		|/// Docs.
+		|module comments
+		|
+		|// The base.
+		|import "base.pkl" // Unused.
+		|
+		|// Standalone
+		|// paragraph.
+		|
+		|// Leading.
+		|/// Docs.
+		|foo = 1 + /* Dropped. */ 2 // Trailing.
+		|
+		|class Bar {
+		|  /*
+		|   * Block.
+		|   */
+		|  baz: Int
+		|
+		|  // Last.
+		|}
+		|
+		|qux { // Opening.
+		|  ["a"] = 1 // Seconds.
+		|  // Before b.
+		|  b = 2
+		|}
+		|
+		|// End.
+	`)
+
+	res := stringsutil.StripMargin(`
+		|#! /usr/bin/env pkl
+		|// Copyright.
+		|
+		|/// Docs.
+		|module comments
+		|
+		|// The base.
+		|import "base.pkl" // Unused.
+		|
+		|// Standalone
+		|// paragraph.
+		|
+		|// Leading.
+		|/// Docs.
+		|foo = 1 + 2 // Trailing.
+		|
+		|class Bar {
+		|  /*
+		|    * Block.
+		|  */
+		|  baz: Int
+		|
+		|  // Last.
+		|}
+		|
+		|qux {
+		|  // Opening.
+		|  ["a"] = 1 // Seconds.
+		|  // Before b.
+		|  b = 2
+		|}
+		|
+		|// End.
+		|
+	`)
+
+	mod, err := ParseFileMode("", []byte(src), ParseComments)
+	assert.NoError(t, err)
+
+	out, err := mod.Marshal(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, res, string(out))
+
+	assert.Equal(t, ast.Comments{ast.LineComment("Copyright.")}, mod.LeadingComments)
+	assert.Empty(t, mod.HeaderComments)
+
+	foo := mod.Members[1].(*ast.ClassProperty)
+	assert.Equal(t, ast.Comments{ast.LineComment("Leading.")}, foo.LeadingComments)
+	assert.Equal(t, ast.LineComment("Trailing."), foo.TrailingComment)
+
+	// Without ParseComments, comments are dropped.
+	mod, err = ParseModule([]byte(src))
+	assert.NoError(t, err)
+	assert.Len(t, mod.Members, 3)
+	assert.Empty(t, mod.LeadingComments)
+}
+
+func TestParseCommentsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// Expected output when it differs from src.
+		res string
+	}{
+		{
+			name: "comments above the header",
+			src: stringsutil.StripMargin(`
+				|// Copyright.
+				|// License.
+				|module header
+				|
+				|x = 1
+				|
+			`),
+		},
+		{
+			name: "comments separated from the header",
+			src: stringsutil.StripMargin(`
+				|// Copyright.
+				|
+				|// Header.
+				|/// Docs.
+				|module header
+				|
+				|x = 1
+				|
+			`),
+		},
+		{
+			name: "empty comments",
+			src: stringsutil.StripMargin(`
+				|module empty
+				|
+				|//
+				|
+				|/**/
+				|
+				|//
+				|a = 1 //
+				|
+				|class B {
+				|  //
+				|
+				|  /**/
+				|  c: Int
+				|}
+			`),
+			res: stringsutil.StripMargin(`
+				|module empty
+				|
+				|//
+				|
+				|/**/
+				|
+				|//
+				|a = 1
+				|
+				|class B {
+				|  //
+				|
+				|  /**/
+				|  c: Int
+				|}
+				|
+			`),
+		},
+		{
+			name: "standalone comments in object",
+			src: stringsutil.StripMargin(`
+				|module objects
+				|
+				|foo {
+				|  a = 1
+				|
+				|  // Standalone.
+				|
+				|  // Leading.
+				|  b = 2
+				|  // After b.
+				|
+				|  c = 3
+				|  /* Last. */
+				|}
+			`),
+			res: stringsutil.StripMargin(`
+				|module objects
+				|
+				|foo {
+				|  a = 1
+				|
+				|  // Standalone.
+				|
+				|  // Leading.
+				|  b = 2
+				|
+				|  // After b.
+				|
+				|  c = 3
+				|
+				|  /*
+				|    Last.
+				|  */
+				|}
+				|
+			`),
+		},
+		{
+			name: "trailing block comment",
+			src: stringsutil.StripMargin(`
+				|module trailing
+				|
+				|a = 1 /* b */ // t
+				|b = 2 /* c */
+				|c = 3 /* d */ /* e */
+			`),
+			res: stringsutil.StripMargin(`
+				|module trailing
+				|
+				|a = 1 // /* b */ // t
+				|
+				|b = 2 // /* c */
+				|
+				|c = 3 // /* d */ /* e */
+				|
+			`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := test.res
+			if expected == "" {
+				expected = test.src
+			}
+
+			mod, err := ParseFileMode("", []byte(test.src), ParseComments)
+			assert.NoError(t, err)
+
+			res, err := mod.Marshal(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(res))
+
+			// Printing the module again gives the same output.
+			mod, err = ParseFileMode("", res, ParseComments)
+			assert.NoError(t, err)
+
+			res, err = mod.Marshal(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(res))
+		})
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	src := stringsutil.StripMargin(`
+		|module positions
+		|
+		|/// Docs.
+		|foo: Int = 1 + bar
+		|
+		|baz {
+		|  ["key"] = value
+		|}
+	`)
+
+	mod, err := ParseFile("positions.pkl", []byte(src))
+	assert.NoError(t, err)
+
+	pos := func(offset, line, column int) ast.Pos {
+		return ast.Pos{Filename: "positions.pkl", Offset: offset, Line: line, Column: column}
+	}
+
+	foo := mod.Members[0].(*ast.ClassProperty)
+	assert.Equal(t, ast.Span{Start: pos(18, 3, 1), End: pos(46, 4, 19)}, ast.Position(foo))
+	assert.Equal(t, ast.Span{Start: pos(33, 4, 6), End: pos(36, 4, 9)}, ast.Position(foo.Type))
+	assert.Equal(t, ast.Span{Start: pos(39, 4, 12), End: pos(46, 4, 19)}, ast.Position(foo.Expression))
+
+	right := foo.Expression.(*ast.BinaryExpression).Right
+	assert.Equal(t, ast.Span{Start: pos(43, 4, 16), End: pos(46, 4, 19)}, ast.Position(right))
+	assert.Equal(t, "positions.pkl:4:16", ast.Position(right).Start.String())
+
+	baz := mod.Members[1].(*ast.ClassProperty)
+	assert.Equal(t, ast.Span{Start: pos(52, 6, 5), End: pos(73, 8, 2)}, ast.Position(baz.Body))
+
+	entry := baz.Body.Members[0]
+	assert.Equal(t, ast.Span{Start: pos(56, 7, 3), End: pos(71, 7, 18)}, ast.Position(entry))
+
+	// Scalar nodes have no position of their own.
+	assert.Equal(t, ast.Span{}, ast.Position(foo.Expression.(*ast.BinaryExpression).Left))
+}
+
+func TestParseFileError(t *testing.T) {
+	_, err := ParseFile("broken.pkl", []byte("foo = ("))
+
+	assert.Equal(t, &Error{Filename: "broken.pkl", Line: 1, Column: 8, Msg: "expected expression, found end of file"}, err)
+	assert.EqualError(t, err, "broken.pkl:1:8: expected expression, found end of file")
+}
+
+func TestParseFileModeAllErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		res   string
+		diags Diagnostics
+	}{
+		{
+			name: "no errors",
+			src:  "module foo\n\nfoo = 1\n",
+			res:  "module foo\n\nfoo = 1\n",
+		},
+		{
+			name: "module members",
+			src: stringsutil.StripMargin(`
+				|module broken
+				|
+				|foo = )
+				|
+				|bar = 1
+				|
+				|class {
+				|}
+				|
+				|/// Docs.
+				|baz = 2 +
+			`),
+			res: stringsutil.StripMargin(`
+				|module broken
+				|
+				|bar = 1
+				|
+			`),
+			diags: Diagnostics{
+				{Line: 3, Column: 7, Msg: "expected expression, found ')'"},
+				{Line: 7, Column: 7, Msg: "expected 'IDENT', found '{'"},
+				{Line: 11, Column: 10, Msg: "expected expression, found end of file"},
+			},
+		},
+		{
+			name: "class members",
+			src: stringsutil.StripMargin(`
+				|module classes
+				|
+				|class Foo {
+				|  foo: = 1
+				|  bar: Int
+				|  function () = 2
+				|}
+			`),
+			res: stringsutil.StripMargin(`
+				|module classes
+				|
+				|class Foo {
+				|  bar: Int
+				|}
+				|
+			`),
+			diags: Diagnostics{
+				{Line: 4, Column: 8, Msg: "expected type, found '='"},
+				{Line: 6, Column: 12, Msg: "expected 'IDENT', found '('"},
+			},
+		},
+		{
+			name: "semicolons in class",
+			src: stringsutil.StripMargin(`
+				|module semicolons
+				|
+				|class A {
+				|  x = 1;
+				|}
+				|
+				|class B { ; }
+				|
+				|class C {
+				|  x: Int;
+				|  y: Int
+				|}
+			`),
+			res: stringsutil.StripMargin(`
+				|module semicolons
+				|
+				|class A {
+				|  x = 1
+				|}
+				|
+				|class B
+				|
+				|class C {
+				|  x: Int
+				|
+				|  y: Int
+				|}
+				|
+			`),
+			diags: Diagnostics{
+				{Line: 4, Column: 8, Msg: "expected class member, found ';'"},
+				{Line: 7, Column: 11, Msg: "expected class member, found ';'"},
+				{Line: 10, Column: 9, Msg: "expected class member, found ';'"},
+			},
+		},
+		{
+			name: "object members",
+			src: stringsutil.StripMargin(`
+				|module objects
+				|
+				|foo {
+				|  bar {
+				|    [1 = 2
+				|    baz = 3
+				|  }
+				|  qux = ; quux = 4
+				|}
+			`),
+			res: stringsutil.StripMargin(`
+				|module objects
+				|
+				|foo {
+				|  bar {
+				|    baz = 3
+				|  }
+				|  quux = 4
+				|}
+				|
+			`),
+			diags: Diagnostics{
+				{Line: 5, Column: 8, Msg: "expected ']', found '='"},
+				{Line: 8, Column: 9, Msg: "expected expression, found ';'"},
+			},
+		},
+		{
+			name: "scanner errors",
+			src:  "module scanner\n\nfoo = ^\nbar = \"baz\nqux = 1\n",
+			res:  "module scanner\n\nqux = 1\n",
+			diags: Diagnostics{
+				{Line: 3, Column: 7, Msg: "invalid character '^'"},
+				{Line: 4, Column: 7, Msg: "string literal not terminated"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mod, err := ParseFileMode("", []byte(test.src), AllErrors)
+			if test.diags == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, test.diags, err)
+			}
+
+			res, err := mod.Marshal(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, string(res))
+		})
+	}
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	diags := Diagnostics{
+		{Filename: "foo.pkl", Line: 1, Column: 2, Msg: "first"},
+		{Line: 3, Column: 4, Msg: "second"},
+	}
+
+	assert.EqualError(t, diags, "foo.pkl:1:2: first (and 1 more diagnostics)")
+	assert.EqualError(t, diags[1], "3:4: second")
+	assert.Equal(t, "error", diags[0].Severity.String())
+}
+
+var spanType = reflect.TypeOf(ast.Span{})
+
+// clearSpans zeroes the spans of every node reachable from v, so parsed
+// trees can be compared with trees built by hand.
+func clearSpans(v any) {
+	clearValueSpans(reflect.ValueOf(v))
+}
+
+func clearValueSpans(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			clearValueSpans(v.Elem())
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			clearValueSpans(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Field(i)
+			if field.Type() == spanType {
+				if field.CanSet() {
+					field.Set(reflect.Zero(spanType))
+				}
+				continue
+			}
+			clearValueSpans(field)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package pkl
 
 import (
 	"context"
+	"errors"
 
 	"github.com/pauloborges/balsamic/ast"
 )
@@ -12,5 +13,16 @@ type Module struct {
 }
 
 func (m *Module) Marshal() ([]byte, error) {
+	if m.AST == nil {
+		return nil, errors.New("pkl: module has no AST")
+	}
 	return m.AST.Marshal(context.Background())
 }
+
+// Validate checks the AST of the module, as in ast.Validate.
+func (m *Module) Validate() error {
+	if m.AST == nil {
+		return errors.New("pkl: module has no AST")
+	}
+	return m.AST.Validate()
+}
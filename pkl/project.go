@@ -34,10 +34,18 @@ func (p *Project) AddModule(m *Module) {
 	p.Modules[m.Path] = m
 }
 
+// Render validates and marshals each module of the project and returns a
+// file system with their source code, by path. It fails on the first module
+// that ast.Module.Validate rejects, such as one with neither a name nor a
+// parent, without rendering it.
 func (p *Project) Render() (fs.FS, error) {
 	fsys := memfs.New()
 
 	for _, m := range p.Modules {
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("validate Pkl module %s: %w", m.Path, err)
+		}
+
 		data, err := m.Marshal()
 		if err != nil {
 			return nil, fmt.Errorf("marshal Pkl module %s: %w", m.Path, err)
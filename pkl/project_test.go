@@ -0,0 +1,102 @@
+package pkl
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+)
+
+func TestProjectRender(t *testing.T) {
+	p := NewProject("test")
+	p.AddModule(&Module{
+		Path: "server.pkl",
+		AST: &ast.Module{
+			Name: "server",
+			Members: ast.ModuleMembers{
+				&ast.ClassProperty{Name: "port", Expression: ast.IntExpression(8080)},
+			},
+		},
+	})
+
+	fsys, err := p.Render()
+	assert.NoError(t, err)
+
+	manifest, err := fs.ReadFile(fsys, "PklProject")
+	assert.NoError(t, err)
+	assert.Equal(t, "amends \"pkl:Project\"\n", string(manifest))
+
+	server, err := fs.ReadFile(fsys, "server.pkl")
+	assert.NoError(t, err)
+	assert.Equal(t, stringsutil.StripMargin(`
+		|module server
+		|
+		|port = 8080
+		|
+	`), string(server))
+}
+
+func TestProjectRenderInvalidModule(t *testing.T) {
+	tests := []struct {
+		name   string
+		module *Module
+		err    string
+	}{
+		{
+			name:   "no AST",
+			module: &Module{Path: "empty.pkl"},
+			err:    "validate Pkl module empty.pkl: pkl: module has no AST",
+		},
+		{
+			name: "no header",
+			module: &Module{
+				Path: "headless.pkl",
+				AST: &ast.Module{
+					Members: ast.ModuleMembers{
+						&ast.ClassProperty{Name: "port", Expression: ast.IntExpression(8080)},
+					},
+				},
+			},
+			err: "validate Pkl module headless.pkl: Name: is required if ParentRelationship is not set",
+		},
+		{
+			name: "modifier on amending module",
+			module: &Module{
+				Path: "config.pkl",
+				AST: &ast.Module{
+					Modifiers:          ast.Modifiers{ast.ModifierOpen},
+					ParentRelationship: ast.ModuleRelationshipAmends,
+					ParentName:         "base.pkl",
+				},
+			},
+			err: `validate Pkl module config.pkl: Modifiers[0]: "open" is not a valid amending module modifier`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewProject("test")
+			p.AddModule(test.module)
+
+			fsys, err := p.Render()
+
+			assert.Nil(t, fsys)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestProjectRenderValidationErrors(t *testing.T) {
+	p := NewProject("test")
+	p.AddModule(&Module{Path: "headless.pkl", AST: &ast.Module{}})
+
+	_, err := p.Render()
+
+	var validationErrs ast.ValidationErrors
+	assert.True(t, errors.As(err, &validationErrs))
+	assert.Len(t, validationErrs, 1)
+}
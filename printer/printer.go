@@ -0,0 +1,36 @@
+// Package printer prints ast nodes as Pkl source code, following a style
+// configuration.
+package printer
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/pauloborges/balsamic/ast"
+)
+
+// Config configures the printer.
+type Config = ast.PrintConfig
+
+// DefaultConfig is the style of the source code printed by Marshal.
+var DefaultConfig = ast.DefaultPrintConfig
+
+// Fprint pretty-prints node to w using config.
+func Fprint(w io.Writer, node ast.Node, config Config) error {
+	b, err := node.Marshal(ast.WithPrintConfig(context.Background(), config))
+	if err != nil {
+		return err
+	}
+
+	b = bytes.TrimRight(b, "\n")
+	if config.FinalNewline && len(b) > 0 {
+		b = append(b, '\n')
+	}
+	if config.LineEnding == ast.LineEndingCRLF {
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte("\r\n"))
+	}
+
+	_, err = w.Write(b)
+	return err
+}
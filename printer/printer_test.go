@@ -0,0 +1,174 @@
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/pauloborges/balsamic/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFprint(t *testing.T) {
+	module := &ast.Module{
+		Name: "Server",
+		Members: ast.ModuleMembers{
+			&ast.Class{
+				Name: ast.Identifier("Options"),
+				Members: []ast.ClassMember{
+					&ast.ClassProperty{Name: ast.Identifier("host"), Type: &ast.DeclaredType{Name: "String"}},
+					&ast.ClassProperty{Name: ast.Identifier("port"), Type: &ast.DeclaredType{Name: "Int"}},
+				},
+			},
+			&ast.ClassProperty{
+				Name: ast.Identifier("options"),
+				Expression: &ast.NewExpression{
+					Body: &ast.ObjectBody{
+						Members: ast.ObjectMembers{
+							&ast.ObjectProperty{Name: "port", Value: ast.IntExpression(8080)},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		node   ast.Node
+		config Config
+		res    string
+	}{
+		{
+			name:   "default",
+			node:   module,
+			config: DefaultConfig,
+			res: stringsutil.StripMargin(`
+				|module Server
+				|
+				|class Options {
+				|  host: String
+				|
+				|  port: Int
+				|}
+				|
+				|options = new {
+				|  port = 8080
+				|}
+				|
+			`),
+		},
+		{
+			name: "tabs and no blank lines",
+			node: module,
+			config: Config{
+				UseTabs:      true,
+				FinalNewline: true,
+			},
+			res: "module Server\n\nclass Options {\n\thost: String\n\tport: Int\n}\noptions = new {\n\tport = 8080\n}\n",
+		},
+		{
+			name: "indent width and blank lines",
+			node: module,
+			config: Config{
+				IndentWidth:  4,
+				FinalNewline: true,
+				BlankLines:   2,
+			},
+			res: stringsutil.StripMargin(`
+				|module Server
+				|
+				|class Options {
+				|    host: String
+				|
+				|
+				|    port: Int
+				|}
+				|
+				|
+				|options = new {
+				|    port = 8080
+				|}
+				|
+			`),
+		},
+		{
+			name: "crlf without final newline",
+			node: module,
+			config: Config{
+				IndentWidth: 2,
+				LineEnding:  ast.LineEndingCRLF,
+			},
+			res: "module Server\r\n\r\nclass Options {\r\n  host: String\r\n  port: Int\r\n}\r\noptions = new {\r\n  port = 8080\r\n}",
+		},
+		{
+			name:   "expression with final newline",
+			node:   ast.IntExpression(42),
+			config: DefaultConfig,
+			res:    "42\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := Fprint(&b, test.node, test.config)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, b.String())
+		})
+	}
+}
+
+func TestFprintMaxWidth(t *testing.T) {
+	module := &ast.Module{
+		Name: "Server",
+		Members: ast.ModuleMembers{
+			&ast.ClassProperty{
+				Name: ast.Identifier("mode"),
+				Type: &ast.UnionType{
+					Members: []ast.Type{
+						ast.StringLiteralType("development"),
+						ast.StringLiteralType("staging"),
+						ast.StringLiteralType("production"),
+					},
+				},
+			},
+			&ast.ClassProperty{
+				Name: ast.Identifier("hosts"),
+				Expression: &ast.MemberAccessExpression{
+					Name: ast.Identifier("List"),
+					Arguments: ast.Expressions{
+						ast.StringExpression("primary.example.com"),
+						ast.StringExpression("secondary.example.com"),
+					},
+				},
+			},
+		},
+	}
+
+	config := DefaultConfig
+	config.MaxWidth = 40
+
+	var b bytes.Buffer
+	err := Fprint(&b, module, config)
+
+	assert.NoError(t, err)
+	assert.Equal(t, stringsutil.StripMargin(`
+		|module Server
+		|
+		|mode: "development"
+		|  | "staging"
+		|  | "production"
+		|
+		|hosts = List(
+		|  "primary.example.com",
+		|  "secondary.example.com"
+		|)
+		|
+	`), b.String())
+
+	_, err = parser.ParseFile("server.pkl", b.Bytes())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package resolve
+
+import "github.com/pauloborges/balsamic/ast"
+
+// builtins are the names of the members of pkl:base, which every module sees
+// without importing it.
+var builtins = []ast.Identifier{
+	// Classes and type aliases.
+	"AlsoKnownAs",
+	"Annotation",
+	"Any",
+	"Boolean",
+	"Bytes",
+	"Char",
+	"Class",
+	"Collection",
+	"Comparable",
+	"DataSize",
+	"DataSizeUnit",
+	"Deprecated",
+	"DocExample",
+	"Duration",
+	"DurationUnit",
+	"Dynamic",
+	"FileOutput",
+	"Float",
+	"Function",
+	"Function0",
+	"Function1",
+	"Function2",
+	"Function3",
+	"Function4",
+	"Function5",
+	"Int",
+	"Int8",
+	"Int16",
+	"Int32",
+	"IntSeq",
+	"JsonRenderer",
+	"List",
+	"Listing",
+	"Map",
+	"Mapping",
+	"Mixin",
+	"Module",
+	"ModuleInfo",
+	"ModuleOutput",
+	"Null",
+	"Number",
+	"Object",
+	"Pair",
+	"PcfRenderDirective",
+	"PcfRenderer",
+	"PListRenderer",
+	"PropertiesRenderer",
+	"Regex",
+	"RegexMatch",
+	"RenderDirective",
+	"Resource",
+	"Set",
+	"Since",
+	"SourceCode",
+	"String",
+	"TypeAlias",
+	"Typed",
+	"UInt",
+	"UInt8",
+	"UInt16",
+	"UInt32",
+	"Unlisted",
+	"Uri",
+	"ValueRenderer",
+	"VarArgs",
+	"YamlRenderer",
+
+	// Properties and methods.
+	"Infinity",
+	"NaN",
+	"TODO",
+	"Undefined",
+
+	// Members of Module, Typed and Any. Every module extends Module and every
+	// class extends Typed, so these are visible everywhere in a module.
+	"getClass",
+	"getProperty",
+	"getPropertyOrNull",
+	"hasProperty",
+	"ifNonNull",
+	"output",
+	"relativePathTo",
+	"toDynamic",
+	"toMap",
+	"toString",
+}
+
+// newUniverse returns the scope of the members of pkl:base and of the members
+// every module inherits, the outermost scope of every module.
+func newUniverse() *Scope {
+	universe := newScope(nil, nil, false)
+	for _, name := range builtins {
+		universe.Symbols = append(universe.Symbols, &Symbol{Name: name, Kind: KindBuiltin, Scope: universe})
+	}
+	return universe
+}
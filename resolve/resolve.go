@@ -0,0 +1,508 @@
+// Package resolve binds the names used in a Pkl module to their
+// declarations.
+//
+// Names are resolved lexically, from the innermost scope outwards: let
+// bindings, function, method and object body parameters, for generator
+// variables, type parameters, the local members of objects, class members,
+// module members and imports, and finally the members of pkl:base and the
+// members every module inherits from Module, Typed and Any.
+//
+// The members inherited from an explicit parent module or class, and the
+// members of the objects amended by object bodies, are not declared in the
+// module, so the names that can refer to them are not reported as
+// unresolved.
+package resolve
+
+import (
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pauloborges/balsamic/ast"
+)
+
+// Resolution is the result of resolving the names of a module.
+type Resolution struct {
+	// The scopes of the module, by the node that opens them.
+	Scopes map[ast.Node]*Scope
+	// The symbols the names refer to, by the *ast.MemberAccessExpression,
+	// *ast.DeclaredType or *ast.Annotation that uses them, or by the
+	// *ast.Class extending them. The names of qualified types, such as
+	// json.Renderer, are bound to the import of their module.
+	Bindings map[ast.Node]*Symbol
+	// The declarations that hide another one with the same name in an
+	// enclosing scope, in the order of the source. The members of pkl:base
+	// can be hidden freely.
+	Shadowings []*Shadowing
+}
+
+// Shadowing is a declaration that hides another one with the same name in an
+// enclosing scope.
+type Shadowing struct {
+	Symbol   *Symbol
+	Shadowed *Symbol
+}
+
+// UnresolvedError is a name that does not refer to any declaration.
+type UnresolvedError struct {
+	// The *ast.MemberAccessExpression, *ast.DeclaredType, *ast.Annotation or
+	// *ast.Class using the name.
+	Node ast.Node
+	Name ast.Identifier
+}
+
+func (e *UnresolvedError) Error() string {
+	msg := "resolve: "
+	if span := ast.Position(e.Node); span.IsValid() {
+		msg += span.Start.String() + ": "
+	}
+	return msg + "unresolved name " + string(e.Name)
+}
+
+// UnresolvedErrors are all the names that Resolve could not resolve, in the
+// order of the source.
+type UnresolvedErrors []*UnresolvedError
+
+func (e UnresolvedErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e UnresolvedErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Resolve builds the scopes of m and binds the names used in it to their
+// declarations. It returns UnresolvedErrors with the names that do not refer
+// to any declaration, along with the resolution of all the others.
+func Resolve(m *ast.Module) (*Resolution, error) {
+	r := resolver{
+		res: &Resolution{
+			Scopes:   map[ast.Node]*Scope{},
+			Bindings: map[ast.Node]*Symbol{},
+		},
+	}
+	r.module(newUniverse(), m)
+
+	if len(r.errs) > 0 {
+		return r.res, r.errs
+	}
+	return r.res, nil
+}
+
+type resolver struct {
+	res  *Resolution
+	errs UnresolvedErrors
+}
+
+// isNil reports whether n is not set, including a nil pointer to a node.
+func isNil(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	val := reflect.ValueOf(n)
+	return val.Kind() == reflect.Pointer && val.IsNil()
+}
+
+func (r *resolver) scope(parent *Scope, n ast.Node, open bool) *Scope {
+	scope := newScope(parent, n, open)
+	r.res.Scopes[n] = scope
+	return scope
+}
+
+// declare declares a symbol in scope, unless it has no name.
+func (r *resolver) declare(scope *Scope, name ast.Identifier, kind Kind, n ast.Node) {
+	if name == "" || name == ast.IdentifierBlank {
+		return
+	}
+
+	sym := &Symbol{Name: name, Kind: kind, Node: n, Scope: scope}
+	for _, ns := range kind.Namespaces() {
+		if scope.Parent == nil {
+			break
+		}
+		if shadowed := scope.Parent.Lookup(name, ns); shadowed != nil && shadowed.Kind != KindBuiltin {
+			r.res.Shadowings = append(r.res.Shadowings, &Shadowing{Symbol: sym, Shadowed: shadowed})
+			break
+		}
+	}
+	scope.Symbols = append(scope.Symbols, sym)
+}
+
+// bind binds a name used by n to the symbol it refers to in scope.
+func (r *resolver) bind(scope *Scope, n ast.Node, name ast.Identifier, ns Namespace) {
+	sym, open := scope.lookup(name, ns)
+	switch {
+	case sym != nil:
+		r.res.Bindings[n] = sym
+	case !open:
+		r.errs = append(r.errs, &UnresolvedError{Node: n, Name: name})
+	}
+}
+
+// importName returns the name an import clause declares: its alias, or the
+// name of the imported module, as in json for "pkl:json" and base for
+// "../base.pkl". Glob imports must have an alias.
+func importName(i *ast.ImportClause) ast.Identifier {
+	if i.Alias != "" {
+		return ast.Identifier(i.Alias)
+	}
+	if i.Glob {
+		return ""
+	}
+
+	name := i.Path
+	if j := strings.LastIndexAny(name, "/:#"); j >= 0 {
+		name = name[j+1:]
+	}
+	return ast.Identifier(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+func (r *resolver) module(universe *Scope, m *ast.Module) {
+	if m == nil {
+		return
+	}
+
+	scope := r.scope(universe, m, m.ParentRelationship != "")
+	for _, i := range m.Imports {
+		if i != nil {
+			r.declare(scope, importName(i), KindImport, i)
+		}
+	}
+	declareMembers(r, scope, m.Members)
+
+	r.annotations(scope, m.Annotations)
+	resolveMembers(r, scope, m.Members)
+}
+
+// declareMembers declares the members of a module or a class in scope,
+// including the members of their member groups.
+func declareMembers[N ast.Node](r *resolver, scope *Scope, members []N) {
+	for _, member := range members {
+		switch member := any(member).(type) {
+		case *ast.ModuleMemberGroup:
+			if member != nil {
+				declareMembers(r, scope, member.Members)
+			}
+		case *ast.ClassMemberGroup:
+			if member != nil {
+				declareMembers(r, scope, member.Members)
+			}
+		case *ast.ClassProperty:
+			if member != nil {
+				r.declare(scope, member.Name, KindProperty, member)
+			}
+		case *ast.ClassMethod:
+			if member != nil && member.Signature != nil {
+				r.declare(scope, member.Signature.Name, KindMethod, member)
+			}
+		case *ast.Class:
+			if member != nil {
+				r.declare(scope, member.Name, KindClass, member)
+			}
+		case *ast.TypeAlias:
+			if member != nil {
+				r.declare(scope, member.Name, KindTypeAlias, member)
+			}
+		}
+	}
+}
+
+// resolveMembers resolves the names used by the members of a module or a
+// class.
+func resolveMembers[N ast.Node](r *resolver, scope *Scope, members []N) {
+	for _, member := range members {
+		if isNil(member) {
+			continue
+		}
+		switch member := any(member).(type) {
+		case *ast.ModuleMemberGroup:
+			resolveMembers(r, scope, member.Members)
+		case *ast.ClassMemberGroup:
+			resolveMembers(r, scope, member.Members)
+		case *ast.ClassProperty:
+			r.annotations(scope, member.Annotations)
+			r.typ(scope, member.Type)
+			r.expr(scope, member.Expression)
+			r.body(scope, member.Body)
+		case *ast.ClassMethod:
+			r.annotations(scope, member.Annotations)
+			r.method(scope, member, member.Signature, member.Implementation)
+		case *ast.Class:
+			r.class(scope, member)
+		case *ast.TypeAlias:
+			r.annotations(scope, member.Annotations)
+			aliasScope := r.scope(scope, member, false)
+			r.typeParameters(aliasScope, member.Parameters)
+			r.typ(aliasScope, member.Type)
+		}
+	}
+}
+
+func (r *resolver) class(scope *Scope, c *ast.Class) {
+	r.annotations(scope, c.Annotations)
+	if c.ParentName != "" {
+		r.typeName(scope, c, c.ParentName)
+	}
+
+	// The members inherited from the parent class are not known.
+	classScope := r.scope(scope, c, c.ParentName != "")
+	r.typeParameters(classScope, c.TypeParameters)
+	declareMembers(r, classScope, c.Members)
+	resolveMembers(r, classScope, c.Members)
+}
+
+// method resolves the names used by a method, declared by n, whose type
+// parameters and parameters are visible in its signature and body.
+func (r *resolver) method(scope *Scope, n ast.Node, sig *ast.MethodSignature, body ast.Expression) {
+	methodScope := r.scope(scope, n, false)
+	if sig != nil {
+		r.typeParameters(methodScope, sig.TypeParameters)
+		r.parameters(methodScope, sig.Parameters)
+		r.typ(methodScope, sig.Result)
+	}
+	r.expr(methodScope, body)
+}
+
+func (r *resolver) typeParameters(scope *Scope, params ast.TypeParameters) {
+	for _, param := range params {
+		if param != nil {
+			r.declare(scope, param.Name, KindTypeParameter, param)
+		}
+	}
+}
+
+// parameters declares the parameters in scope. Their types are resolved in
+// scope too, as they can use the type parameters of a method.
+func (r *resolver) parameters(scope *Scope, params ast.Parameters) {
+	for _, param := range params {
+		r.parameter(scope, param)
+	}
+}
+
+func (r *resolver) parameter(scope *Scope, param *ast.Parameter) {
+	if param == nil {
+		return
+	}
+	r.typ(scope, param.Type)
+	r.declare(scope, param.Name, KindParameter, param)
+}
+
+func (r *resolver) annotations(scope *Scope, annotations ast.Annotations) {
+	for _, a := range annotations {
+		if a != nil {
+			r.typeName(scope, a, a.Name)
+			r.body(scope, a.Body)
+		}
+	}
+}
+
+// body resolves the names used by an object body. The members of the object
+// it amends are not known, so its scope is open.
+func (r *resolver) body(scope *Scope, body *ast.ObjectBody) {
+	if body == nil {
+		return
+	}
+
+	bodyScope := r.scope(scope, body, true)
+	r.parameters(bodyScope, body.Parameters)
+	r.declareObjectMembers(bodyScope, body.Members)
+	r.objectMembers(bodyScope, body.Members)
+}
+
+func (r *resolver) bodies(scope *Scope, bodies []*ast.ObjectBody) {
+	for _, body := range bodies {
+		r.body(scope, body)
+	}
+}
+
+// declareObjectMembers declares the local members of an object in scope.
+// The other members amend the members of the object, so they declare no
+// names.
+func (r *resolver) declareObjectMembers(scope *Scope, members ast.ObjectMembers) {
+	for _, member := range members {
+		switch member := member.(type) {
+		case *ast.ObjectMemberGroup:
+			if member != nil {
+				r.declareObjectMembers(scope, member.Members)
+			}
+		case *ast.ObjectProperty:
+			if member != nil && slices.Contains(member.Modifiers, ast.ModifierLocal) {
+				r.declare(scope, member.Name, KindProperty, member)
+			}
+		case *ast.ObjectMethod:
+			if member != nil && member.Signature != nil && slices.Contains(member.Signature.Modifiers, ast.ModifierLocal) {
+				r.declare(scope, member.Signature.Name, KindMethod, member)
+			}
+		}
+	}
+}
+
+func (r *resolver) objectMembers(scope *Scope, members ast.ObjectMembers) {
+	for _, member := range members {
+		if isNil(member) {
+			continue
+		}
+		switch member := member.(type) {
+		case *ast.ObjectMemberGroup:
+			r.objectMembers(scope, member.Members)
+		case *ast.ObjectProperty:
+			r.typ(scope, member.Type)
+			r.expr(scope, member.Value)
+			r.bodies(scope, member.Body)
+		case *ast.ObjectMethod:
+			r.method(scope, member, member.Signature, member.Value)
+		case *ast.ObjectEntry:
+			r.expr(scope, member.Key)
+			r.expr(scope, member.Value)
+			r.bodies(scope, member.Body)
+		case *ast.ObjectElement:
+			r.expr(scope, member.Value)
+		case *ast.ObjectSpread:
+			r.expr(scope, member.Value)
+		case *ast.MemberPredicate:
+			r.expr(scope, member.Condition)
+			r.expr(scope, member.Value)
+			r.bodies(scope, member.Body)
+		case *ast.ForGenerator:
+			r.expr(scope, member.Collection)
+			forScope := r.scope(scope, member, false)
+			r.parameter(forScope, member.Key)
+			r.parameter(forScope, member.Value)
+			r.body(forScope, member.Body)
+		case *ast.WhenGenerator:
+			r.expr(scope, member.Condition)
+			r.body(scope, member.Then)
+			r.body(scope, member.Else)
+		}
+	}
+}
+
+func (r *resolver) exprs(scope *Scope, exprs ast.Expressions) {
+	for _, e := range exprs {
+		r.expr(scope, e)
+	}
+}
+
+func (r *resolver) expr(scope *Scope, e ast.Expression) {
+	if isNil(e) {
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.MemberAccessExpression:
+		if e.Arguments != nil {
+			r.bind(scope, e, e.Name, NamespaceMethod)
+		} else {
+			r.bind(scope, e, e.Name, NamespaceValue)
+		}
+		r.exprs(scope, e.Arguments)
+	case *ast.QualifiedMemberAccessExpression:
+		r.expr(scope, e.Receiver)
+		r.exprs(scope, e.Arguments)
+	case *ast.SuperAccessExpression:
+		r.exprs(scope, e.Arguments)
+	case *ast.SubscriptExpression:
+		r.expr(scope, e.Receiver)
+		r.expr(scope, e.Subscript)
+	case *ast.SuperSubscriptExpression:
+		r.expr(scope, e.Subscript)
+	case *ast.InterpolatedStringExpression:
+		for _, part := range e.Parts {
+			if part, ok := part.(*ast.StringInterpolation); ok && part != nil {
+				r.expr(scope, part.Expression)
+			}
+		}
+	case *ast.PrefixUnaryExpression:
+		r.expr(scope, e.Operand)
+	case *ast.PostfixUnaryExpression:
+		r.expr(scope, e.Operand)
+	case *ast.BinaryExpression:
+		r.expr(scope, e.Left)
+		r.expr(scope, e.Right)
+	case *ast.TypeExpression:
+		r.expr(scope, e.Expression)
+		r.typ(scope, e.Type)
+	case *ast.ParenthesizedExpression:
+		r.expr(scope, e.Expression)
+	case *ast.NewExpression:
+		r.typ(scope, e.Type)
+		r.body(scope, e.Body)
+	case *ast.AmendExpression:
+		if parent, ok := e.Parent.(ast.Expression); ok {
+			r.expr(scope, parent)
+		}
+		r.body(scope, e.Body)
+	case *ast.IfExpression:
+		r.expr(scope, e.Condition)
+		r.expr(scope, e.Then)
+		r.expr(scope, e.Else)
+	case *ast.LetExpression:
+		// The value is resolved before the binding is declared, as it can
+		// not refer to it.
+		r.expr(scope, e.Value)
+		letScope := r.scope(scope, e, false)
+		r.parameter(letScope, e.Name)
+		r.expr(letScope, e.Expression)
+	case *ast.FunctionLiteralExpression:
+		funcScope := r.scope(scope, e, false)
+		r.parameters(funcScope, e.Parameters)
+		r.expr(funcScope, e.Body)
+	case *ast.ReadExpression:
+		r.expr(scope, e.Value)
+	case *ast.ThrowExpression:
+		r.expr(scope, e.Value)
+	case *ast.TraceExpression:
+		r.expr(scope, e.Value)
+	}
+}
+
+func (r *resolver) typ(scope *Scope, t ast.Type) {
+	if isNil(t) {
+		return
+	}
+
+	switch t := t.(type) {
+	case *ast.DeclaredType:
+		r.typeName(scope, t, t.Name)
+		for _, param := range t.TypeParameters {
+			r.typ(scope, param)
+		}
+	case *ast.ParenthesizedType:
+		r.typ(scope, t.Type)
+	case *ast.NullableType:
+		r.typ(scope, t.Type)
+	case *ast.ConstrainedType:
+		r.typ(scope, t.Type)
+		// The constraints can use the members of the constrained value, which
+		// are not known.
+		r.exprs(r.scope(scope, t, true), t.Constraints)
+	case *ast.UnionType:
+		for _, member := range t.Members {
+			r.typ(scope, member)
+		}
+		r.typ(scope, t.Default)
+	case *ast.FunctionLiteralType:
+		for _, param := range t.Parameters {
+			r.typ(scope, param)
+		}
+		r.typ(scope, t.Result)
+	}
+}
+
+// typeName binds the name of a type used by n. A qualified name is bound to
+// the import of its module.
+func (r *resolver) typeName(scope *Scope, n ast.Node, name ast.QualifiedIdentifier) {
+	first, _, _ := strings.Cut(string(name), ".")
+	if first != "" {
+		r.bind(scope, n, ast.Identifier(first), NamespaceType)
+	}
+}
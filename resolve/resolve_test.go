@@ -0,0 +1,273 @@
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/ast"
+	"github.com/pauloborges/balsamic/internal/stringsutil"
+	"github.com/pauloborges/balsamic/parser"
+)
+
+// describe returns the bindings and shadowings of a resolution as lines
+// sorted by position, such as "4:13 port -> property 3:1".
+func describe(res *Resolution) (bindings, shadowings []string) {
+	for n, sym := range res.Bindings {
+		bindings = append(bindings, fmt.Sprintf("%s %s -> %s", ast.Position(n).Start, sym.Name, describeSymbol(sym)))
+	}
+	slices.SortFunc(bindings, compareLines)
+
+	for _, s := range res.Shadowings {
+		shadowings = append(shadowings, fmt.Sprintf("%s %s", describeSymbol(s.Symbol), describeSymbol(s.Shadowed)))
+	}
+	return bindings, shadowings
+}
+
+func describeSymbol(sym *Symbol) string {
+	if sym.Node == nil {
+		return string(sym.Kind)
+	}
+	return fmt.Sprintf("%s %s", sym.Kind, ast.Position(sym.Node).Start)
+}
+
+// compareLines compares lines starting with a "line:column" position.
+func compareLines(a, b string) int {
+	var aLine, aCol, bLine, bCol int
+	fmt.Sscanf(a, "%d:%d", &aLine, &aCol)
+	fmt.Sscanf(b, "%d:%d", &bLine, &bCol)
+	if aLine != bLine {
+		return aLine - bLine
+	}
+	return aCol - bCol
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		bindings   []string
+		shadowings []string
+		err        string
+	}{
+		{
+			name: "module",
+			src: stringsutil.StripMargin(`
+				|module test
+				|
+				|import "pkl:json"
+				|import "lib/base.pkl" as lib
+				|
+				|port: Int = 8080
+				|
+				|function inc(n: Int): Int = n + 1
+				|
+				|typealias Port = Int(isBetween(0, 65535))
+				|
+				|class Server<T> {
+				|  port: Port = inc(port)
+				|  value: T?
+				|  renderer: json.Renderer
+				|}
+				|
+				|servers: Listing<Server> = new {
+				|  for (i, s in List(1, 2)) {
+				|    s + i + offset
+				|  }
+				|}
+				|
+				|hosts = let (h = port) h * 2
+				|
+				|fn = (port) -> port + lib.base
+			`),
+			bindings: []string{
+				"6:7 Int -> builtin",
+				"8:17 Int -> builtin",
+				"8:23 Int -> builtin",
+				"8:29 n -> parameter 8:14",
+				"10:18 Int -> builtin",
+				"13:9 Port -> type alias 10:1",
+				"13:16 inc -> method 8:1",
+				"13:20 port -> property 13:3",
+				"14:10 T -> type parameter 12:14",
+				"15:13 json -> import 3:1",
+				"18:10 Listing -> builtin",
+				"18:18 Server -> class 12:1",
+				"19:16 List -> builtin",
+				"20:5 s -> parameter 19:11",
+				"20:9 i -> parameter 19:8",
+				"24:18 port -> property 6:1",
+				"24:24 h -> parameter 24:14",
+				"26:16 port -> parameter 26:7",
+				"26:23 lib -> import 4:1",
+			},
+			shadowings: []string{
+				"property 13:3 property 6:1",
+				"parameter 26:7 property 6:1",
+			},
+		},
+		{
+			name: "objects",
+			src: stringsutil.StripMargin(`
+				|local x = 1
+				|
+				|obj {
+				|  local x = x + 1
+				|  y = x
+				|  local function double<T>(n: T): T = n
+				|  z = double(y)
+				|  default { key ->
+				|    name = key
+				|  }
+				|}
+			`),
+			bindings: []string{
+				"4:13 x -> property 4:3",
+				"5:7 x -> property 4:3",
+				"6:31 T -> type parameter 6:25",
+				"6:35 T -> type parameter 6:25",
+				"6:39 n -> parameter 6:28",
+				"7:7 double -> method 6:3",
+				"9:12 key -> parameter 8:13",
+			},
+			shadowings: []string{
+				"property 4:3 property 1:1",
+			},
+		},
+		{
+			name: "unresolved",
+			src: stringsutil.StripMargin(`
+				|a: Missing = b
+				|
+				|function f(x) = x + y(z)
+				|
+				|c {
+				|  d = e
+				|}
+				|
+				|f = let (g = g) g
+			`),
+			bindings: []string{
+				"3:17 x -> parameter 3:12",
+				"9:17 g -> parameter 9:10",
+			},
+			err: stringsutil.StripMargin(`
+				|resolve: 1:4: unresolved name Missing
+				|resolve: 1:14: unresolved name b
+				|resolve: 3:21: unresolved name y
+				|resolve: 3:23: unresolved name z
+				|resolve: 9:14: unresolved name g
+			`),
+		},
+		{
+			name: "implicit parents",
+			src: stringsutil.StripMargin(`
+				|x = output.text
+				|z = toString()
+				|function b() = getClass()
+				|c = hasProperty("b")
+				|d = relativePathTo(module)
+				|
+				|class C {
+				|  e = getPropertyOrNull("e") ?? toMap()
+				|}
+			`),
+			bindings: []string{
+				"1:5 output -> builtin",
+				"2:5 toString -> builtin",
+				"3:16 getClass -> builtin",
+				"4:5 hasProperty -> builtin",
+				"5:5 relativePathTo -> builtin",
+				"8:7 getPropertyOrNull -> builtin",
+				"8:33 toMap -> builtin",
+			},
+		},
+		{
+			name: "annotations",
+			src: stringsutil.StripMargin(`
+				|import "pkl:json"
+				|
+				|@Deprecated { message = "old" }
+				|a = 1
+				|
+				|@json.Hidden
+				|b = 2
+				|
+				|@Since { version = "0.10.0" }
+				|@DocExample { subjects { "b" } }
+				|c = 4
+				|
+				|@Nope
+				|class C {
+				|  @Local
+				|  d = 3
+				|}
+				|
+				|class Local extends Annotation
+			`),
+			bindings: []string{
+				"3:1 Deprecated -> builtin",
+				"6:1 json -> import 1:1",
+				"9:1 Since -> builtin",
+				"10:1 DocExample -> builtin",
+				"15:3 Local -> class 19:1",
+				"19:1 Annotation -> builtin",
+			},
+			err: "resolve: 13:1: unresolved name Nope",
+		},
+		{
+			name: "inherited",
+			src: stringsutil.StripMargin(`
+				|amends "base.pkl"
+				|
+				|a = b
+				|
+				|local class C extends D {
+				|  e: Int = f
+				|}
+			`),
+			bindings: []string{
+				"6:6 Int -> builtin",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := parser.ParseModule([]byte(test.src))
+			assert.NoError(t, err)
+
+			res, err := Resolve(m)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+
+			bindings, shadowings := describe(res)
+			assert.Equal(t, test.bindings, bindings)
+			assert.Equal(t, test.shadowings, shadowings)
+		})
+	}
+}
+
+func TestUnresolvedErrors(t *testing.T) {
+	m := &ast.Module{
+		Name: "test",
+		Members: ast.ModuleMembers{
+			&ast.ClassProperty{Name: "a", Expression: &ast.MemberAccessExpression{Name: "b"}},
+			&ast.ClassProperty{Name: "c", Type: &ast.DeclaredType{Name: "D"}},
+		},
+	}
+
+	_, err := Resolve(m)
+
+	assert.EqualError(t, err, "resolve: unresolved name b\nresolve: unresolved name D")
+
+	var unresolvedErr *UnresolvedError
+	assert.True(t, errors.As(err, &unresolvedErr))
+	assert.Equal(t, ast.Identifier("b"), unresolvedErr.Name)
+}
@@ -0,0 +1,115 @@
+package resolve
+
+import (
+	"slices"
+
+	"github.com/pauloborges/balsamic/ast"
+)
+
+// Kind is the kind of declaration of a symbol.
+type Kind string
+
+const (
+	KindImport        Kind = "import"
+	KindProperty      Kind = "property"
+	KindMethod        Kind = "method"
+	KindClass         Kind = "class"
+	KindTypeAlias     Kind = "type alias"
+	KindTypeParameter Kind = "type parameter"
+	KindParameter     Kind = "parameter"
+	// A member of pkl:base, which every module sees.
+	KindBuiltin Kind = "builtin"
+)
+
+// Namespace is a set of names that do not clash with the names of the other
+// namespaces: a property and a method, for instance, can have the same name.
+type Namespace string
+
+const (
+	// The names of properties and of the values of variables.
+	NamespaceValue Namespace = "value"
+	// The names of methods.
+	NamespaceMethod Namespace = "method"
+	// The names of types.
+	NamespaceType Namespace = "type"
+)
+
+// Namespaces returns the namespaces the symbols of the kind are declared in.
+// Imports, classes and type aliases can be used both as values and as types.
+func (k Kind) Namespaces() []Namespace {
+	switch k {
+	case KindImport, KindClass, KindTypeAlias:
+		return []Namespace{NamespaceValue, NamespaceType}
+	case KindProperty, KindParameter:
+		return []Namespace{NamespaceValue}
+	case KindMethod:
+		return []Namespace{NamespaceMethod}
+	case KindTypeParameter:
+		return []Namespace{NamespaceType}
+	case KindBuiltin:
+		return []Namespace{NamespaceValue, NamespaceMethod, NamespaceType}
+	}
+	return nil
+}
+
+// Symbol is a declaration that names can refer to.
+type Symbol struct {
+	Name ast.Identifier
+	Kind Kind
+	// The declaring node, such as *ast.ImportClause, *ast.ClassProperty or
+	// *ast.Parameter. Nil for builtins.
+	Node ast.Node
+	// The scope the symbol is declared in.
+	Scope *Scope
+}
+
+// Scope is a symbol table: the symbols declared by a node, such as a module,
+// a class or a let expression, visible to the nodes inside it.
+type Scope struct {
+	// The enclosing scope. Nil for the scope of the members of pkl:base.
+	Parent *Scope
+	// The node that opens the scope, such as *ast.Module or
+	// *ast.LetExpression. Nil for the scope of the members of pkl:base.
+	Node ast.Node
+	// Whether names not declared in the scope can still be visible in it,
+	// inherited from a parent module or class, or from the object amended by
+	// an object body.
+	Open bool
+	// The symbols declared in the scope, in the order of the source.
+	Symbols []*Symbol
+}
+
+func newScope(parent *Scope, n ast.Node, open bool) *Scope {
+	return &Scope{Parent: parent, Node: n, Open: open}
+}
+
+// LookupLocal returns the symbol declared in the scope with name in ns, or
+// nil if there is none. If there are many, it returns the first one.
+func (s *Scope) LookupLocal(name ast.Identifier, ns Namespace) *Symbol {
+	for _, sym := range s.Symbols {
+		if sym.Name == name && slices.Contains(sym.Kind.Namespaces(), ns) {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Lookup returns the symbol with name in ns declared in the scope or in the
+// innermost of its enclosing scopes, or nil if there is none.
+func (s *Scope) Lookup(name ast.Identifier, ns Namespace) *Symbol {
+	sym, _ := s.lookup(name, ns)
+	return sym
+}
+
+// lookup is like Lookup, but also reports whether the name can be visible
+// even if there is no symbol for it, as one of the scopes looked up is open.
+func (s *Scope) lookup(name ast.Identifier, ns Namespace) (*Symbol, bool) {
+	open := false
+	for scope := s; scope != nil; scope = scope.Parent {
+		if sym := scope.LookupLocal(name, ns); sym != nil {
+			return sym, open
+		}
+		open = open || scope.Open
+	}
+	return nil, open
+}
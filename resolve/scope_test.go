@@ -0,0 +1,87 @@
+package resolve
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/ast"
+)
+
+func TestScopeLookup(t *testing.T) {
+	module := newScope(newUniverse(), nil, false)
+	module.Symbols = []*Symbol{
+		{Name: "foo", Kind: KindProperty},
+		{Name: "foo", Kind: KindMethod},
+		{Name: "Bar", Kind: KindClass},
+	}
+	body := newScope(module, nil, true)
+	body.Symbols = []*Symbol{
+		{Name: "foo", Kind: KindParameter},
+	}
+
+	tests := []struct {
+		name      string
+		scope     *Scope
+		ident     ast.Identifier
+		namespace Namespace
+		kind      Kind
+		open      bool
+	}{
+		{
+			name:      "local",
+			scope:     body,
+			ident:     "foo",
+			namespace: NamespaceValue,
+			kind:      KindParameter,
+		},
+		{
+			name:      "enclosing",
+			scope:     body,
+			ident:     "foo",
+			namespace: NamespaceMethod,
+			kind:      KindMethod,
+			open:      true,
+		},
+		{
+			name:      "class as value",
+			scope:     module,
+			ident:     "Bar",
+			namespace: NamespaceValue,
+			kind:      KindClass,
+		},
+		{
+			name:      "builtin",
+			scope:     module,
+			ident:     "List",
+			namespace: NamespaceMethod,
+			kind:      KindBuiltin,
+		},
+		{
+			name:      "missing",
+			scope:     module,
+			ident:     "foo",
+			namespace: NamespaceType,
+		},
+		{
+			name:      "missing in open scope",
+			scope:     body,
+			ident:     "baz",
+			namespace: NamespaceValue,
+			open:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sym, open := test.scope.lookup(test.ident, test.namespace)
+
+			if test.kind == "" {
+				assert.Nil(t, sym)
+			} else if assert.NotNil(t, sym) {
+				assert.Equal(t, test.kind, sym.Kind)
+			}
+			assert.Equal(t, test.open, open)
+		})
+	}
+}
@@ -0,0 +1,552 @@
+// Package scanner implements a scanner for Pkl source code. It produces a
+// token stream even for source code that does not parse: invalid input is
+// reported to an ErrorHandler and scanned as ILLEGAL tokens.
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pauloborges/balsamic/token"
+)
+
+// ErrorHandler is called for every error found while scanning.
+type ErrorHandler func(pos token.Pos, msg string)
+
+// Mode controls the scanner behavior.
+type Mode uint
+
+const (
+	// ScanComments makes the scanner return line and block comments as
+	// tokens instead of skipping them. Shebang and doc comments are always
+	// returned.
+	ScanComments Mode = 1 << iota
+)
+
+// frame is a lexical context the scanner is nested in: a string literal or
+// an interpolation inside a string literal.
+type frame struct {
+	start         token.Pos
+	interpolation bool
+	// Number of '#' of the string delimiters.
+	pounds    int
+	multiline bool
+	// Number of open parentheses in the interpolation.
+	depth int
+}
+
+// Scanner tokenizes Pkl source code.
+type Scanner struct {
+	src  []byte
+	err  ErrorHandler
+	mode Mode
+
+	offset int
+	line   int
+	column int
+
+	// Set when a newline was skipped since the last non-comment token.
+	newline bool
+
+	frames []frame
+
+	// Number of errors found.
+	ErrorCount int
+}
+
+// Init prepares the scanner to tokenize src. Errors are reported to err
+// when it is not nil.
+func (s *Scanner) Init(src []byte, err ErrorHandler, mode Mode) {
+	s.src = src
+	s.err = err
+	s.mode = mode
+	s.offset = 0
+	s.line = 1
+	s.column = 1
+	s.newline = false
+	s.frames = nil
+	s.ErrorCount = 0
+}
+
+// Tokenize returns all tokens of src, ending with EOF. Errors are reported to
+// err when it is not nil.
+func Tokenize(src []byte, err ErrorHandler, mode Mode) []token.Token {
+	var s Scanner
+	s.Init(src, err, mode)
+
+	var toks []token.Token
+	for {
+		tok := s.Scan()
+		toks = append(toks, tok)
+		if tok.Kind == token.EOF {
+			return toks
+		}
+	}
+}
+
+func (s *Scanner) pos() token.Pos {
+	return token.Pos{Offset: s.offset, Line: s.line, Column: s.column}
+}
+
+func (s *Scanner) error(pos token.Pos, format string, args ...any) {
+	s.ErrorCount++
+	if s.err != nil {
+		s.err(pos, fmt.Sprintf(format, args...))
+	}
+}
+
+// peek returns the byte at offset n from the current position, or 0 when
+// it is out of bounds.
+func (s *Scanner) peek(n int) byte {
+	if s.offset+n < len(s.src) {
+		return s.src[s.offset+n]
+	}
+	return 0
+}
+
+func (s *Scanner) advance(n int) {
+	for range n {
+		if s.offset >= len(s.src) {
+			return
+		}
+		if s.src[s.offset] == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+		s.offset++
+	}
+}
+
+func (s *Scanner) hasPrefix(prefix string) bool {
+	return len(s.src)-s.offset >= len(prefix) && string(s.src[s.offset:s.offset+len(prefix)]) == prefix
+}
+
+func (s *Scanner) top() *frame {
+	if len(s.frames) == 0 {
+		return nil
+	}
+	return &s.frames[len(s.frames)-1]
+}
+
+func (s *Scanner) pop() {
+	s.frames = s.frames[:len(s.frames)-1]
+}
+
+// skip skips whitespace, and comments unless they are scanned as tokens.
+func (s *Scanner) skip() {
+	for s.offset < len(s.src) {
+		switch c := s.src[s.offset]; {
+		case c == '\n':
+			s.newline = true
+			s.advance(1)
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			s.advance(1)
+		case s.mode&ScanComments == 0 && isLineComment(s.src[s.offset:]):
+			s.scanLineComment()
+		case s.mode&ScanComments == 0 && s.hasPrefix("/*"):
+			if s.scanBlockComment() {
+				s.newline = true
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isDocComment(src []byte) bool {
+	return len(src) >= 3 && string(src[:3]) == "///" && (len(src) == 3 || src[3] != '/')
+}
+
+func isLineComment(src []byte) bool {
+	return len(src) >= 2 && string(src[:2]) == "//" && !isDocComment(src)
+}
+
+func (s *Scanner) scanLineComment() {
+	for s.offset < len(s.src) && s.src[s.offset] != '\n' {
+		s.advance(1)
+	}
+}
+
+// scanBlockComment scans a possibly nested block comment and reports
+// whether it spans several lines.
+func (s *Scanner) scanBlockComment() bool {
+	start := s.pos()
+	depth := 0
+
+	for s.offset < len(s.src) {
+		switch {
+		case s.hasPrefix("/*"):
+			depth++
+			s.advance(2)
+		case s.hasPrefix("*/"):
+			depth--
+			s.advance(2)
+			if depth == 0 {
+				return s.line > start.Line
+			}
+		default:
+			s.advance(1)
+		}
+	}
+
+	s.error(start, "block comment not terminated")
+	return s.line > start.Line
+}
+
+// Scan returns the next token. At the end of the source it returns EOF.
+func (s *Scanner) Scan() token.Token {
+	var kind token.Kind
+	var tok token.Token
+
+	if f := s.top(); f != nil && !f.interpolation {
+		tok.Pos = s.pos()
+		kind = s.scanStringPart(f)
+	} else {
+		s.skip()
+		tok.Pos = s.pos()
+		tok.Newline = s.newline
+		kind = s.scan()
+	}
+
+	tok.Kind = kind
+	tok.Text = string(s.src[tok.Pos.Offset:s.offset])
+	tok.End = s.pos()
+
+	if !kind.IsComment() {
+		s.newline = false
+	}
+	if kind == token.BLOCK_COMMENT && tok.End.Line > tok.Pos.Line {
+		s.newline = true
+	}
+
+	return tok
+}
+
+func (s *Scanner) scan() token.Kind {
+	if s.offset >= len(s.src) {
+		if f := s.top(); f != nil {
+			s.error(f.start, "string interpolation not terminated")
+			s.frames = nil
+		}
+		return token.EOF
+	}
+
+	c := s.src[s.offset]
+
+	switch {
+	case s.offset == 0 && s.hasPrefix("#!"):
+		s.scanLineComment()
+		return token.SHEBANG
+	case isDocComment(s.src[s.offset:]):
+		s.scanLineComment()
+		return token.DOC_COMMENT
+	case isLineComment(s.src[s.offset:]):
+		s.scanLineComment()
+		return token.LINE_COMMENT
+	case s.hasPrefix("/*"):
+		s.scanBlockComment()
+		return token.BLOCK_COMMENT
+	case c == '`':
+		return s.scanQuotedIdentifier()
+	case isIdentifierStart(s.rune()):
+		return s.scanIdentifier()
+	case isDigit(c) || (c == '.' && isDigit(s.peek(1))):
+		return s.scanNumber()
+	case c == '"' || c == '#':
+		return s.scanStringStart()
+	}
+
+	kind := s.scanOperator()
+
+	if f := s.top(); f != nil {
+		switch kind {
+		case token.LPAREN:
+			f.depth++
+		case token.RPAREN:
+			if f.depth == 0 {
+				s.pop()
+				return token.INTERPOLATION_END
+			}
+			f.depth--
+		}
+	}
+
+	return kind
+}
+
+func (s *Scanner) rune() rune {
+	r, _ := utf8.DecodeRune(s.src[s.offset:])
+	return r
+}
+
+func (s *Scanner) advanceRune() {
+	_, size := utf8.DecodeRune(s.src[s.offset:])
+	s.advance(size)
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (s *Scanner) scanIdentifier() token.Kind {
+	start := s.offset
+	for s.offset < len(s.src) && isIdentifierPart(s.rune()) {
+		s.advanceRune()
+	}
+
+	kind := token.Lookup(string(s.src[start:s.offset]))
+	switch {
+	case kind == token.IMPORT && s.peek(0) == '*':
+		s.advance(1)
+		return token.IMPORT_GLOB
+	case kind == token.READ && s.peek(0) == '?':
+		s.advance(1)
+		return token.READ_NULL
+	case kind == token.READ && s.peek(0) == '*':
+		s.advance(1)
+		return token.READ_GLOB
+	}
+
+	return kind
+}
+
+func (s *Scanner) scanQuotedIdentifier() token.Kind {
+	start := s.pos()
+	s.advance(1)
+
+	for s.offset < len(s.src) {
+		switch s.src[s.offset] {
+		case '`':
+			s.advance(1)
+			return token.IDENT
+		case '\n':
+			s.error(start, "quoted identifier not terminated")
+			return token.ILLEGAL
+		}
+		s.advance(1)
+	}
+
+	s.error(start, "quoted identifier not terminated")
+	return token.ILLEGAL
+}
+
+func (s *Scanner) scanDigits(valid func(byte) bool) int {
+	n := 0
+	for s.offset < len(s.src) && (valid(s.src[s.offset]) || s.src[s.offset] == '_') {
+		if s.src[s.offset] != '_' {
+			n++
+		}
+		s.advance(1)
+	}
+	return n
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isBinaryDigit(c byte) bool { return c == '0' || c == '1' }
+
+func isOctalDigit(c byte) bool { return '0' <= c && c <= '7' }
+
+func (s *Scanner) scanNumber() token.Kind {
+	start := s.pos()
+
+	if s.peek(0) == '0' {
+		var valid func(byte) bool
+		switch s.peek(1) {
+		case 'x', 'X':
+			valid = isHexDigit
+		case 'b', 'B':
+			valid = isBinaryDigit
+		case 'o', 'O':
+			valid = isOctalDigit
+		}
+		if valid != nil {
+			s.advance(2)
+			if s.scanDigits(valid) == 0 {
+				s.error(start, "invalid number literal %q", s.src[start.Offset:s.offset])
+				return token.ILLEGAL
+			}
+			return token.INT
+		}
+	}
+
+	kind := token.INT
+	s.scanDigits(isDigit)
+
+	if s.peek(0) == '.' && isDigit(s.peek(1)) {
+		kind = token.FLOAT
+		s.advance(1)
+		s.scanDigits(isDigit)
+	}
+
+	if c := s.peek(0); c == 'e' || c == 'E' {
+		kind = token.FLOAT
+		s.advance(1)
+		if c := s.peek(0); c == '+' || c == '-' {
+			s.advance(1)
+		}
+		if s.scanDigits(isDigit) == 0 {
+			s.error(start, "invalid number literal %q", s.src[start.Offset:s.offset])
+			return token.ILLEGAL
+		}
+	}
+
+	return kind
+}
+
+func (s *Scanner) scanStringStart() token.Kind {
+	start := s.pos()
+
+	pounds := 0
+	for s.peek(pounds) == '#' {
+		pounds++
+	}
+	if s.peek(pounds) != '"' {
+		s.advance(pounds)
+		s.error(start, "invalid character %q", '#')
+		return token.ILLEGAL
+	}
+	s.advance(pounds)
+
+	multiline := s.hasPrefix(`"""`)
+	if multiline {
+		s.advance(3)
+	} else {
+		s.advance(1)
+	}
+
+	s.frames = append(s.frames, frame{start: start, pounds: pounds, multiline: multiline})
+
+	return token.STRING_START
+}
+
+func (f *frame) closing() string {
+	if f.multiline {
+		return `"""` + strings.Repeat("#", f.pounds)
+	}
+	return `"` + strings.Repeat("#", f.pounds)
+}
+
+func (f *frame) escape() string {
+	return `\` + strings.Repeat("#", f.pounds)
+}
+
+// scanStringPart scans the next token inside a string literal.
+func (s *Scanner) scanStringPart(f *frame) token.Kind {
+	closing := f.closing()
+	escape := f.escape()
+
+	switch {
+	case s.hasPrefix(closing):
+		s.advance(len(closing))
+		s.pop()
+		return token.STRING_END
+
+	case s.hasPrefix(escape + "("):
+		s.frames = append(s.frames, frame{start: s.pos(), interpolation: true})
+		s.advance(len(escape) + 1)
+		return token.INTERPOLATION_START
+
+	case s.offset >= len(s.src) || (!f.multiline && s.src[s.offset] == '\n'):
+		// Close the string so scanning can go on with regular tokens.
+		s.error(f.start, "string literal not terminated")
+		s.pop()
+		return token.STRING_END
+
+	case s.hasPrefix(escape):
+		s.advance(len(escape))
+		if s.hasPrefix("u{") {
+			for s.offset < len(s.src) && s.src[s.offset] != '}' && s.src[s.offset] != '"' && s.src[s.offset] != '\n' {
+				s.advance(1)
+			}
+			if s.peek(0) == '}' {
+				s.advance(1)
+			}
+		} else if s.offset < len(s.src) {
+			s.advanceRune()
+		}
+		return token.STRING_ESCAPE
+	}
+
+	for s.offset < len(s.src) {
+		if s.hasPrefix(closing) || s.hasPrefix(escape) || (!f.multiline && s.src[s.offset] == '\n') {
+			break
+		}
+		s.advanceRune()
+	}
+
+	return token.STRING_PART
+}
+
+// operators lists the operator tokens, longest first so the scanner always
+// picks the longest match.
+var operators = []token.Kind{
+	token.QSPREAD,
+	token.SPREAD,
+	token.LPRED,
+	token.QDOT,
+	token.COALESCE,
+	token.NON_NULL,
+	token.ARROW,
+	token.EQL,
+	token.NEQ,
+	token.LEQ,
+	token.GEQ,
+	token.POW,
+	token.INT_DIV,
+	token.LAND,
+	token.LOR,
+	token.PIPE,
+	token.LPAREN,
+	token.RPAREN,
+	token.LBRACE,
+	token.RBRACE,
+	token.LBRACK,
+	token.RBRACK,
+	token.COMMA,
+	token.DOT,
+	token.NOT,
+	token.ASSIGN,
+	token.LSS,
+	token.GTR,
+	token.ADD,
+	token.SUB,
+	token.MUL,
+	token.QUO,
+	token.REM,
+	token.OR,
+	token.AND,
+	token.AT,
+	token.COLON,
+	token.SEMICOLON,
+	token.QUESTION,
+}
+
+func (s *Scanner) scanOperator() token.Kind {
+	for _, op := range operators {
+		if s.hasPrefix(op.String()) {
+			s.advance(len(op.String()))
+			return op
+		}
+	}
+
+	start := s.pos()
+	r := s.rune()
+	s.advanceRune()
+	s.error(start, "invalid character %q", r)
+	return token.ILLEGAL
+}
@@ -0,0 +1,202 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pauloborges/balsamic/token"
+)
+
+type tok struct {
+	kind token.Kind
+	text string
+}
+
+func tokens(src string, mode Mode) ([]tok, []string) {
+	var errs []string
+	handler := func(pos token.Pos, msg string) {
+		errs = append(errs, msg)
+	}
+
+	var res []tok
+	for _, t := range Tokenize([]byte(src), handler, mode) {
+		res = append(res, tok{t.Kind, t.Text})
+	}
+	return res, errs
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		mode Mode
+		res  []tok
+		errs []string
+	}{
+		{
+			name: "keywords and identifiers",
+			src:  "import* read? foo `bar baz` typealias",
+			res: []tok{
+				{token.IMPORT_GLOB, "import*"},
+				{token.READ_NULL, "read?"},
+				{token.IDENT, "foo"},
+				{token.IDENT, "`bar baz`"},
+				{token.TYPEALIAS, "typealias"},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "numbers",
+			src:  "42 0x1F 0b1_0 1.5e3 .5",
+			res: []tok{
+				{token.INT, "42"},
+				{token.INT, "0x1F"},
+				{token.INT, "0b1_0"},
+				{token.FLOAT, "1.5e3"},
+				{token.FLOAT, ".5"},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "operators",
+			src:  "a ?? b |> c ~/ d ** e?.f!! ...?g [[",
+			res: []tok{
+				{token.IDENT, "a"},
+				{token.COALESCE, "??"},
+				{token.IDENT, "b"},
+				{token.PIPE, "|>"},
+				{token.IDENT, "c"},
+				{token.INT_DIV, "~/"},
+				{token.IDENT, "d"},
+				{token.POW, "**"},
+				{token.IDENT, "e"},
+				{token.QDOT, "?."},
+				{token.IDENT, "f"},
+				{token.NON_NULL, "!!"},
+				{token.QSPREAD, "...?"},
+				{token.IDENT, "g"},
+				{token.LPRED, "[["},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "comments skipped",
+			src:  "#!/usr/bin/env pkl\n/// docs\n// line\n/* block */ foo",
+			res: []tok{
+				{token.SHEBANG, "#!/usr/bin/env pkl"},
+				{token.DOC_COMMENT, "/// docs"},
+				{token.IDENT, "foo"},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "comments scanned",
+			src:  "// line\n/* outer /* inner */ */ foo",
+			mode: ScanComments,
+			res: []tok{
+				{token.LINE_COMMENT, "// line"},
+				{token.BLOCK_COMMENT, "/* outer /* inner */ */"},
+				{token.IDENT, "foo"},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "string",
+			src:  `"foo\n\u{1F600}"`,
+			res: []tok{
+				{token.STRING_START, `"`},
+				{token.STRING_PART, "foo"},
+				{token.STRING_ESCAPE, `\n`},
+				{token.STRING_ESCAPE, `\u{1F600}`},
+				{token.STRING_END, `"`},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "interpolation",
+			src:  `"a\(f(b))c"`,
+			res: []tok{
+				{token.STRING_START, `"`},
+				{token.STRING_PART, "a"},
+				{token.INTERPOLATION_START, `\(`},
+				{token.IDENT, "f"},
+				{token.LPAREN, "("},
+				{token.IDENT, "b"},
+				{token.RPAREN, ")"},
+				{token.INTERPOLATION_END, ")"},
+				{token.STRING_PART, "c"},
+				{token.STRING_END, `"`},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "custom delimiters",
+			src:  `#"a"\#(x)"#`,
+			res: []tok{
+				{token.STRING_START, `#"`},
+				{token.STRING_PART, `a"`},
+				{token.INTERPOLATION_START, `\#(`},
+				{token.IDENT, "x"},
+				{token.INTERPOLATION_END, ")"},
+				{token.STRING_END, `"#`},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "multiline string",
+			src:  "\"\"\"\n  foo \"\n  \"\"\"",
+			res: []tok{
+				{token.STRING_START, `"""`},
+				{token.STRING_PART, "\n  foo \"\n  "},
+				{token.STRING_END, `"""`},
+				{token.EOF, ""},
+			},
+		},
+		{
+			name: "unterminated string",
+			src:  "foo = \"bar\nbaz",
+			res: []tok{
+				{token.IDENT, "foo"},
+				{token.ASSIGN, "="},
+				{token.STRING_START, `"`},
+				{token.STRING_PART, "bar"},
+				{token.STRING_END, ""},
+				{token.IDENT, "baz"},
+				{token.EOF, ""},
+			},
+			errs: []string{"string literal not terminated"},
+		},
+		{
+			name: "illegal character",
+			src:  "foo ^ bar",
+			res: []tok{
+				{token.IDENT, "foo"},
+				{token.ILLEGAL, "^"},
+				{token.IDENT, "bar"},
+				{token.EOF, ""},
+			},
+			errs: []string{"invalid character '^'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, errs := tokens(test.src, test.mode)
+
+			assert.Equal(t, test.res, res)
+			assert.Equal(t, test.errs, errs)
+		})
+	}
+}
+
+func TestScanPositions(t *testing.T) {
+	toks := Tokenize([]byte("foo\n  // bar\n  baz"), nil, 0)
+
+	assert.Equal(t, token.Pos{Offset: 0, Line: 1, Column: 1}, toks[0].Pos)
+	assert.Equal(t, token.Pos{Offset: 3, Line: 1, Column: 4}, toks[0].End)
+	assert.False(t, toks[0].Newline)
+
+	assert.Equal(t, token.Pos{Offset: 15, Line: 3, Column: 3}, toks[1].Pos)
+	assert.True(t, toks[1].Newline)
+}
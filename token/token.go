@@ -0,0 +1,304 @@
+// Package token defines the lexical tokens of the Pkl language.
+package token
+
+import "strconv"
+
+// Kind is the set of lexical tokens of the Pkl language.
+type Kind int
+
+const (
+	ILLEGAL Kind = iota
+	EOF
+
+	literalBeg
+	SHEBANG       // #!/usr/bin/env pkl
+	DOC_COMMENT   // /// docs
+	LINE_COMMENT  // // comment
+	BLOCK_COMMENT // /* comment */
+	IDENT         // foo, `foo bar`
+	INT           // 42, 0xff
+	FLOAT         // 3.14, 1e10
+	literalEnd
+
+	// A string literal is scanned as STRING_START, followed by any number of
+	// STRING_PART, STRING_ESCAPE and interpolations, and STRING_END. An
+	// interpolation is INTERPOLATION_START, the tokens of the interpolated
+	// expression and INTERPOLATION_END.
+	stringBeg
+	STRING_START        // ", #", """, #"""
+	STRING_PART         // literal text, including line breaks
+	STRING_ESCAPE       // \n, \#t, \u{1F600}
+	INTERPOLATION_START // \(, \#(
+	INTERPOLATION_END   // )
+	STRING_END          // ", "#, """, """#
+	stringEnd
+
+	operatorBeg
+	LPAREN    // (
+	RPAREN    // )
+	LBRACE    // {
+	RBRACE    // }
+	LBRACK    // [
+	RBRACK    // ]
+	LPRED     // [[
+	COMMA     // ,
+	DOT       // .
+	QDOT      // ?.
+	COALESCE  // ??
+	NOT       // !
+	NON_NULL  // !!
+	SPREAD    // ...
+	QSPREAD   // ...?
+	ARROW     // ->
+	ASSIGN    // =
+	EQL       // ==
+	NEQ       // !=
+	LSS       // <
+	LEQ       // <=
+	GTR       // >
+	GEQ       // >=
+	ADD       // +
+	SUB       // -
+	MUL       // *
+	POW       // **
+	QUO       // /
+	INT_DIV   // ~/
+	REM       // %
+	LAND      // &&
+	LOR       // ||
+	PIPE      // |>
+	OR        // |
+	AND       // &
+	AT        // @
+	COLON     // :
+	SEMICOLON // ;
+	QUESTION  // ?
+	operatorEnd
+
+	keywordBeg
+	ABSTRACT
+	AMENDS
+	AS
+	CLASS
+	CONST
+	ELSE
+	EXTENDS
+	EXTERNAL
+	FALSE
+	FIXED
+	FOR
+	FUNCTION
+	HIDDEN
+	IF
+	IMPORT
+	IMPORT_GLOB
+	IN
+	IS
+	LET
+	LOCAL
+	MODULE
+	NEW
+	NOTHING
+	NULL
+	OPEN
+	OUT
+	OUTER
+	READ
+	READ_NULL
+	READ_GLOB
+	SUPER
+	THIS
+	THROW
+	TRACE
+	TRUE
+	TYPEALIAS
+	UNKNOWN
+	WHEN
+
+	// Reserved for future use by the language.
+	CASE
+	DELETE
+	OVERRIDE
+	PROTECTED
+	RECORD
+	SWITCH
+	VARARG
+	keywordEnd
+)
+
+var kinds = [...]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+
+	SHEBANG:       "SHEBANG",
+	DOC_COMMENT:   "DOC_COMMENT",
+	LINE_COMMENT:  "LINE_COMMENT",
+	BLOCK_COMMENT: "BLOCK_COMMENT",
+	IDENT:         "IDENT",
+	INT:           "INT",
+	FLOAT:         "FLOAT",
+
+	STRING_START:        "STRING_START",
+	STRING_PART:         "STRING_PART",
+	STRING_ESCAPE:       "STRING_ESCAPE",
+	INTERPOLATION_START: "INTERPOLATION_START",
+	INTERPOLATION_END:   "INTERPOLATION_END",
+	STRING_END:          "STRING_END",
+
+	LPAREN:    "(",
+	RPAREN:    ")",
+	LBRACE:    "{",
+	RBRACE:    "}",
+	LBRACK:    "[",
+	RBRACK:    "]",
+	LPRED:     "[[",
+	COMMA:     ",",
+	DOT:       ".",
+	QDOT:      "?.",
+	COALESCE:  "??",
+	NOT:       "!",
+	NON_NULL:  "!!",
+	SPREAD:    "...",
+	QSPREAD:   "...?",
+	ARROW:     "->",
+	ASSIGN:    "=",
+	EQL:       "==",
+	NEQ:       "!=",
+	LSS:       "<",
+	LEQ:       "<=",
+	GTR:       ">",
+	GEQ:       ">=",
+	ADD:       "+",
+	SUB:       "-",
+	MUL:       "*",
+	POW:       "**",
+	QUO:       "/",
+	INT_DIV:   "~/",
+	REM:       "%",
+	LAND:      "&&",
+	LOR:       "||",
+	PIPE:      "|>",
+	OR:        "|",
+	AND:       "&",
+	AT:        "@",
+	COLON:     ":",
+	SEMICOLON: ";",
+	QUESTION:  "?",
+
+	ABSTRACT:    "abstract",
+	AMENDS:      "amends",
+	AS:          "as",
+	CLASS:       "class",
+	CONST:       "const",
+	ELSE:        "else",
+	EXTENDS:     "extends",
+	EXTERNAL:    "external",
+	FALSE:       "false",
+	FIXED:       "fixed",
+	FOR:         "for",
+	FUNCTION:    "function",
+	HIDDEN:      "hidden",
+	IF:          "if",
+	IMPORT:      "import",
+	IMPORT_GLOB: "import*",
+	IN:          "in",
+	IS:          "is",
+	LET:         "let",
+	LOCAL:       "local",
+	MODULE:      "module",
+	NEW:         "new",
+	NOTHING:     "nothing",
+	NULL:        "null",
+	OPEN:        "open",
+	OUT:         "out",
+	OUTER:       "outer",
+	READ:        "read",
+	READ_NULL:   "read?",
+	READ_GLOB:   "read*",
+	SUPER:       "super",
+	THIS:        "this",
+	THROW:       "throw",
+	TRACE:       "trace",
+	TRUE:        "true",
+	TYPEALIAS:   "typealias",
+	UNKNOWN:     "unknown",
+	WHEN:        "when",
+
+	CASE:      "case",
+	DELETE:    "delete",
+	OVERRIDE:  "override",
+	PROTECTED: "protected",
+	RECORD:    "record",
+	SWITCH:    "switch",
+	VARARG:    "vararg",
+}
+
+// String returns the string corresponding to the token kind. For operators
+// and keywords it is the actual token text.
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kinds) && kinds[k] != "" {
+		return kinds[k]
+	}
+	return "token(" + strconv.Itoa(int(k)) + ")"
+}
+
+// IsLiteral reports whether the kind is an identifier, a number or a
+// comment.
+func (k Kind) IsLiteral() bool { return literalBeg < k && k < literalEnd }
+
+// IsComment reports whether the kind is a comment, including shebang and doc
+// comments.
+func (k Kind) IsComment() bool {
+	return k == SHEBANG || k == DOC_COMMENT || k == LINE_COMMENT || k == BLOCK_COMMENT
+}
+
+// IsString reports whether the kind is part of a string literal. The tokens
+// of interpolated expressions are not.
+func (k Kind) IsString() bool { return stringBeg < k && k < stringEnd }
+
+// IsOperator reports whether the kind is an operator or delimiter.
+func (k Kind) IsOperator() bool { return operatorBeg < k && k < operatorEnd }
+
+// IsKeyword reports whether the kind is a keyword, including the reserved
+// ones.
+func (k Kind) IsKeyword() bool { return keywordBeg < k && k < keywordEnd }
+
+var keywords map[string]Kind
+
+func init() {
+	keywords = make(map[string]Kind, keywordEnd-keywordBeg)
+	for k := keywordBeg + 1; k < keywordEnd; k++ {
+		keywords[kinds[k]] = k
+	}
+}
+
+// Lookup maps an identifier to its keyword kind, or IDENT if it is not a
+// keyword.
+func Lookup(ident string) Kind {
+	if k, ok := keywords[ident]; ok {
+		return k
+	}
+	return IDENT
+}
+
+// Pos describes a location in the source code. Line and Column are 1-based,
+// Column counts bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// Token is a lexical token with its literal source text.
+type Token struct {
+	Kind Kind
+	// Literal source text of the token.
+	Text string
+	// Position of the first byte of the token.
+	Pos Pos
+	// Position immediately after the last byte of the token.
+	End Pos
+	// Set if there is at least one newline between the previous non-comment
+	// token and this one.
+	Newline bool
+}
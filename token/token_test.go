@@ -0,0 +1,94 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name  string
+		ident string
+		res   Kind
+	}{
+		{
+			name:  "identifier",
+			ident: "foo",
+			res:   IDENT,
+		},
+		{
+			name:  "keyword",
+			ident: "typealias",
+			res:   TYPEALIAS,
+		},
+		{
+			name:  "reserved keyword",
+			ident: "switch",
+			res:   SWITCH,
+		},
+		{
+			name:  "case sensitive",
+			ident: "When",
+			res:   IDENT,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, Lookup(test.ident))
+		})
+	}
+}
+
+func TestKindString(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Kind
+		res  string
+	}{
+		{
+			name: "literal",
+			kind: IDENT,
+			res:  "IDENT",
+		},
+		{
+			name: "operator",
+			kind: COALESCE,
+			res:  "??",
+		},
+		{
+			name: "keyword",
+			kind: IMPORT_GLOB,
+			res:  "import*",
+		},
+		{
+			name: "unknown",
+			kind: Kind(-1),
+			res:  "token(-1)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.res, test.kind.String())
+		})
+	}
+}
+
+func TestKindClasses(t *testing.T) {
+	assert.True(t, INT.IsLiteral())
+	assert.False(t, INT.IsOperator())
+
+	assert.True(t, PIPE.IsOperator())
+	assert.False(t, PIPE.IsKeyword())
+
+	assert.True(t, AMENDS.IsKeyword())
+	assert.False(t, AMENDS.IsLiteral())
+
+	assert.True(t, DOC_COMMENT.IsComment())
+	assert.False(t, STRING_PART.IsComment())
+
+	assert.True(t, INTERPOLATION_END.IsString())
+	assert.False(t, RPAREN.IsString())
+}